@@ -0,0 +1,182 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Assembler puts events that are logged over several lines back together,
+// e.g., PHP errors with the "PHP Stack trace:" and numbered frame lines that
+// follow them. Lines are matched up by the source and syslog tag (which
+// includes the PID) they were logged with, so the traces of concurrent workers
+// don't get mixed up. An event is held back until it is complete, i.e., until
+// its process logs the start of another one or a line is logged more than
+// Window after its last one. If the log goes quiet, events are released once
+// nothing has been read for Window.
+//
+// It also links lines that belong together without holding them back, e.g.,
+// postfix's lines about a message, as a message can sit in the queue for
+// days. What it remembers for those is forgotten once nothing has been
+// logged about it for long enough.
+//
+// Events take part by implementing assembledInterface; all others are
+// returned as they are.
+type Assembler struct {
+	Window time.Duration
+
+	mutex    sync.Mutex
+	pending  map[string]*pendingEvent
+	order    []string
+	newest   time.Time // log time of the latest line added
+	received time.Time // when the latest line was added
+	evicted  time.Time // log time of the last evict
+	links    map[string]linkedInterface
+}
+
+// assembledInterface is implemented by events that the Assembler has to put
+// back together with other lines, or link to what other lines said.
+type assembledInterface interface {
+	// assemble adds the event to a, whose mutex is held, and returns the
+	// events that are complete as a result.
+	assemble(a *Assembler) []LogEventInterface
+}
+
+// linkedInterface is implemented by what the Assembler remembers, in links,
+// about lines that it links together, e.g., a postfix message.
+type linkedInterface interface {
+	// stale reports whether nothing has been logged about it for too long,
+	// given the log time of the latest line added.
+	stale(newest time.Time) bool
+}
+
+type pendingEvent struct {
+	event LogEventInterface
+
+	// last is the log time of the latest line of the event.
+	last time.Time
+}
+
+func NewAssembler(window time.Duration) *Assembler {
+	return &Assembler{
+		Window:  window,
+		pending: make(map[string]*pendingEvent),
+		links:   make(map[string]linkedInterface),
+	}
+}
+
+// Add takes the next event read from the log and returns the events that are
+// complete as a result, in the order they were logged.
+func (a *Assembler) Add(event LogEventInterface) []LogEventInterface {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if event.GetSyslogTime().After(a.newest) {
+		a.newest = event.GetSyslogTime()
+	}
+
+	a.received = time.Now()
+
+	if a.newest.Sub(a.evicted) > time.Hour {
+		a.evict()
+		a.evicted = a.newest
+	}
+
+	return append(a.expired(), a.add(event)...)
+}
+
+// evict forgets the links nothing has been logged about for too long, e.g.,
+// because the line saying a message was removed was missed.
+func (a *Assembler) evict() {
+	for key, link := range a.links {
+		if link.stale(a.newest) {
+			delete(a.links, key)
+		}
+	}
+}
+
+// expired returns the events whose last line was logged more than Window
+// before the latest line added.
+func (a *Assembler) expired() []LogEventInterface {
+	complete := []LogEventInterface{}
+
+	// Releasing events changes a.order, so go over a copy of it.
+	for _, key := range append([]string(nil), a.order...) {
+		if a.newest.Sub(a.pending[key].last) > a.Window {
+			complete = append(complete, a.release(key)...)
+		}
+	}
+
+	return complete
+}
+
+func (a *Assembler) add(event LogEventInterface) []LogEventInterface {
+	if event, ok := event.(assembledInterface); ok {
+		return event.assemble(a)
+	}
+
+	return []LogEventInterface{event}
+}
+
+// hold holds back event under key until it is complete.
+func (a *Assembler) hold(key string, event LogEventInterface) {
+	a.pending[key] = &pendingEvent{
+		event: event,
+		last:  event.GetSyslogTime(),
+	}
+	a.order = append(a.order, key)
+}
+
+// Expire returns every event that is still held back if nothing has been
+// added within Window of now, so that errors aren't held back indefinitely
+// when the log goes quiet.
+func (a *Assembler) Expire(now time.Time) []LogEventInterface {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	complete := []LogEventInterface{}
+
+	if now.Sub(a.received) <= a.Window {
+		return complete
+	}
+
+	for len(a.order) > 0 {
+		complete = append(complete, a.release(a.order[0])...)
+	}
+
+	return complete
+}
+
+// Flush returns every event that is still held back, e.g., once the end of
+// the log has been reached.
+func (a *Assembler) Flush() []LogEventInterface {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	complete := []LogEventInterface{}
+
+	for len(a.order) > 0 {
+		complete = append(complete, a.release(a.order[0])...)
+	}
+
+	return complete
+}
+
+// release stops holding back the event for key, if there is one.
+func (a *Assembler) release(key string) []LogEventInterface {
+	pending, ok := a.pending[key]
+
+	if !ok {
+		return nil
+	}
+
+	delete(a.pending, key)
+
+	for i, pendingKey := range a.order {
+		if pendingKey == key {
+			a.order = append(a.order[:i], a.order[i+1:]...)
+			break
+		}
+	}
+
+	return []LogEventInterface{pending.event}
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC)
+
+// parseTestLine parses message as if it was logged by web1 seconds after
+// testTime.
+func parseTestLine(t *testing.T, seconds float64, message string) LogEventInterface {
+	header := SyslogHeader{
+		SyslogTime: testTime.Add(time.Duration(seconds * float64(time.Second))),
+		Source:     "web1",
+		Facility:   -1,
+		Severity:   -1,
+	}
+
+	event := ParseEvent(header, message)
+
+	if event == nil {
+		t.Fatalf("ParseEvent(%q) = nil", message)
+	}
+
+	return event
+}
+
+// addTestLines adds the lines to assembler, returning the summaries of the
+// events released, in order.
+func addTestLines(t *testing.T, assembler *Assembler, lines []testLine) []string {
+	summaries := []string{}
+
+	for _, line := range lines {
+		for _, event := range assembler.Add(parseTestLine(t, line.seconds, line.message)) {
+			summaries = append(summaries, event.Summary())
+		}
+	}
+
+	return summaries
+}
+
+type testLine struct {
+	seconds float64
+	message string
+}
+
+// countLinks returns how many of the links the assembler remembers are of the
+// same type as link.
+func countLinks(assembler *Assembler, link linkedInterface) int {
+	count := 0
+
+	for _, remembered := range assembler.links {
+		if reflect.TypeOf(remembered) == reflect.TypeOf(link) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func summariesOf(events []LogEventInterface) []string {
+	summaries := []string{}
+
+	for _, event := range events {
+		summaries = append(summaries, event.Summary())
+	}
+
+	return summaries
+}
+
+func TestAssemblerStackTrace(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	released := addTestLines(t, assembler, []testLine{
+		{0, "php: PHP Fatal error:  Call to undefined function foo() in /var/www/index.php on line 3"},
+		{0, "php: PHP Stack trace:"},
+		{0, "php: PHP   1. {main}() /var/www/index.php:0"},
+		{0, "php: PHP   2. bar() /var/www/index.php:10"},
+	})
+
+	if len(released) != 0 {
+		t.Fatalf("released %q before the trace was complete", released)
+	}
+
+	complete := assembler.Flush()
+
+	if len(complete) != 1 {
+		t.Fatalf("Flush() = %q, want one error", summariesOf(complete))
+	}
+
+	if frames := complete[0].(*PhpLogEvent).StackTraceEvents; len(frames) != 2 {
+		t.Errorf("error has %d frames, want 2", len(frames))
+	}
+}
+
+// TestAssemblerLogOrder checks that a held back error is released as soon as
+// a line is logged more than Window after it, ahead of that line.
+func TestAssemblerLogOrder(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	released := addTestLines(t, assembler, []testLine{
+		{0, "php: PHP Warning:  Division by zero in /var/www/index.php on line 3"},
+		{1, "kernel: [ 100.000000] eth0: link up"},
+		{5, "kernel: [ 105.000000] eth0: link down"},
+	})
+
+	want := []string{"kernel-other", "php-Warning", "kernel-other"}
+
+	if !reflect.DeepEqual(released, want) {
+		t.Errorf("released %q, want %q", released, want)
+	}
+}
+
+// TestAssemblerExpire checks that held back errors are only released by the
+// clock when nothing has been added for Window.
+func TestAssemblerExpire(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	addTestLines(t, assembler, []testLine{
+		{0, "php: PHP Warning:  Division by zero in /var/www/index.php on line 3"},
+	})
+
+	if complete := assembler.Expire(time.Now()); len(complete) != 0 {
+		t.Errorf("Expire(now) = %q, want nothing", summariesOf(complete))
+	}
+
+	complete := assembler.Expire(time.Now().Add(3 * time.Second))
+
+	if !reflect.DeepEqual(summariesOf(complete), []string{"php-Warning"}) {
+		t.Errorf("Expire(now + 3s) = %q, want the warning", summariesOf(complete))
+	}
+}
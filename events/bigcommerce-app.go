@@ -1,10 +1,13 @@
 package events
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,25 +15,34 @@ import (
 	"github.com/lovek323/bclog/settings"
 )
 
+var bigcommerceAppTagRegexp = regexp.MustCompile("^(?P<name>[^:\\[]+): (?P<content>.*)$")
+var bigcommerceAppRegexp = regexp.MustCompile("^BigcommerceApp\\.(?P<logLevel>.*?): (?P<content>.*)$")
+
 type BigcommerceAppLogEvent struct {
-	SyslogTime      time.Time
+	SyslogHeader
 	ProcessId       int
+	Channel         string
 	LogLevel        string
 	Content         string
-	Args            string
+	Args            interface{} // decoded JSON object or list, or nil
 	StoreContext    BigcommerceAppStoreContext
 	OriginalMessage string
 }
 
+// BigcommerceAppStoreContext is the store the app was serving when it logged
+// an event. Fields holds everything in the context, including anything
+// nested, as decoded JSON.
 type BigcommerceAppStoreContext struct {
-	StoreId   int    `json:"store_id,string"`
-	StoreHash string `json:"store_hash"`
+	StoreId   int
+	StoreHash string
 	Domain    string
+	Fields    map[string]interface{}
 }
 
 func (e *BigcommerceAppLogEvent) PrintLine(index int) {
 	fmt.Printf("[%d]  ", index)
 	fmt.Printf("%s  ", e.SyslogTime.Format("2006-01-02 15:04:05"))
+	fmt.Printf("%s  ", e.Source)
 	ct.ChangeColor(ct.Yellow, false, ct.None, false)
 	fmt.Print("bigcommerce-app  ")
 	ct.ChangeColor(ct.Cyan, false, ct.None, false)
@@ -45,10 +57,19 @@ func (e *BigcommerceAppLogEvent) PrintFull() {
 		"SyslogTime: %s\n",
 		e.SyslogTime.Format("2006-01-02 15:04:05"),
 	)
+	fmt.Printf("Source:     %s\n", e.Source)
 	fmt.Printf("ProcessId:  %d\n", e.ProcessId)
+	fmt.Printf("Channel:    %s\n", e.Channel)
 	fmt.Printf("LogLevel:   %s\n", e.LogLevel)
 	fmt.Printf("Content:    %s\n", e.Content)
-	fmt.Printf("Args:       %s\n", e.Args)
+	fmt.Printf("Args:       %s\n", formatJsonValue(e.Args))
+
+	if args, ok := e.Args.(map[string]interface{}); ok {
+		for _, key := range sortedKeys(args) {
+			fmt.Printf("  %s: %s\n", key, formatJsonValue(args[key]))
+		}
+	}
+
 	fmt.Printf("StoreId:    %d\n", e.StoreContext.StoreId)
 	fmt.Printf("StoreHash:  %s\n", e.StoreContext.StoreHash)
 	fmt.Printf("Domain:     %s\n", e.StoreContext.Domain)
@@ -74,70 +95,111 @@ func (e *BigcommerceAppLogEvent) Suppress(
 	return false
 }
 
-func (e *BigcommerceAppLogEvent) GetSyslogTime() time.Time {
-	return e.SyslogTime
+// GetField returns the channel, level, the store_id, store_hash and domain of the
+// store context, and args by their key, e.g., args.job_id. Keys of nested
+// args are joined with dots, and list elements are numbered from 0.
+func (e *BigcommerceAppLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "channel":
+		return e.Channel, true
+	case "level":
+		return e.LogLevel, true
+	case "store_id":
+		return strconv.Itoa(e.StoreContext.StoreId), true
+	case "store_hash":
+		return e.StoreContext.StoreHash, true
+	case "domain":
+		return e.StoreContext.Domain, true
+	}
+
+	if !strings.HasPrefix(name, "args.") {
+		return "", false
+	}
+
+	value, ok := lookupJsonValue(e.Args, strings.TrimPrefix(name, "args."))
+
+	if !ok {
+		return "", false
+	}
+
+	return formatJsonValue(value), true
 }
 
-func NewBigcommerceAppLogEvent(
-	syslogTime time.Time,
-	source string,
-	processId int,
-	message string,
-) *BigcommerceAppLogEvent {
-	re := regexp.MustCompile(
-		"^BigcommerceApp\\.(?P<logLevel>.*?): (?P<content>.*?) " +
-			"(?P<args>\\[\\]|\\{.*?\\}) (?P<storeContext>\\{.*?\\})$",
-	)
+func init() {
+	RegisterParser(Parser{
+		Name:     "bigcommerce-app",
+		Priority: 20,
+		Match:    parseBigcommerceAppLogEvent,
+	})
+}
 
-	matches := re.FindStringSubmatch(message)
+func parseBigcommerceAppLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	name, processId, content, ok := splitProcessMessage(message)
 
-	args := ""
-	storeContextJson := ""
+	// The app may log without a PID.
+	if !ok {
+		matches := bigcommerceAppTagRegexp.FindStringSubmatch(message)
 
-	if matches != nil {
-		storeContextJson = matches[4]
-	} else {
-		re = regexp.MustCompile(
-			"^BigcommerceApp\\.(?P<logLevel>.*?): (?P<content>.*?) " +
-			"(?P<args>.+)$",
-		)
-		matches = re.FindStringSubmatch(message)
 		if matches == nil {
-			re = regexp.MustCompile("^BigcommerceApp\\.(?P<logLevel>.*?): (?P<content>.+)$")
-			matches = re.FindStringSubmatch(message)
-			if matches == nil {
-				return nil
-			}
-		} else {
-			args = matches[3]
+			return nil
 		}
+
+		name = matches[1]
+		content = matches[2]
 	}
 
-	logLevel := matches[1]
-	content := matches[2]
+	if name != "bigcommerce_app" && name != "ool bigcommerce_app" {
+		return nil
+	}
 
-	// Replace NULL with 0
-	storeContextJson = strings.Replace(storeContextJson, "NULL", "0", -1)
+	if event := NewBigcommerceAppLogEvent(header, processId, content); event != nil {
+		return event
+	}
 
-	var storeContext BigcommerceAppStoreContext
+	// Records from Monolog's JSON formatter. Anything else could be a PHP
+	// error as well.
+	if event := NewBigcommerceAppJsonLogEvent(header, processId, content); event != nil {
+		return event
+	}
 
-	if storeContextJson != "" {
-		err := json.Unmarshal([]byte(storeContextJson), &storeContext)
+	return nil
+}
 
-		if err != nil {
-			log.Printf(
-				"Could not parse store context: %s (%s)\n",
-				storeContextJson,
-				err,
-			)
+// NewBigcommerceAppLogEvent parses messages from the app's Monolog line
+// formatter, i.e., "BigcommerceApp.LEVEL: content args storeContext", where
+// args and the store context are JSON and either or both can be missing.
+func NewBigcommerceAppLogEvent(
+	header SyslogHeader,
+	processId int,
+	message string,
+) *BigcommerceAppLogEvent {
+	matches := bigcommerceAppRegexp.FindStringSubmatch(message)
 
-			return nil
-		}
+	if matches == nil {
+		return nil
+	}
+
+	logLevel := matches[1]
+	content, values := splitTrailingJson(matches[2])
+
+	var args interface{}
+	var storeContext BigcommerceAppStoreContext
+
+	if len(values) > 0 {
+		args = values[0]
+	}
+
+	if len(values) > 1 {
+		storeContext = newBigcommerceAppStoreContext(values[1])
 	}
 
 	return &BigcommerceAppLogEvent{
-		SyslogTime:      syslogTime,
+		SyslogHeader:    header,
 		ProcessId:       processId,
+		Channel:         "BigcommerceApp",
 		LogLevel:        logLevel,
 		Content:         content,
 		Args:            args,
@@ -145,3 +207,233 @@ func NewBigcommerceAppLogEvent(
 		OriginalMessage: message,
 	}
 }
+
+// monologRecord is a record written by Monolog's JSON formatter.
+type monologRecord struct {
+	Message   *string     `json:"message"`
+	Context   interface{} `json:"context"`
+	LevelName string      `json:"level_name"`
+	Channel   string      `json:"channel"`
+	Datetime  interface{} `json:"datetime"`
+	Extra     interface{} `json:"extra"`
+}
+
+// NewBigcommerceAppJsonLogEvent parses records from Monolog's JSON formatter,
+// optionally prefixed with "@cee:". The context becomes the event's args and
+// the extra data its store context.
+func NewBigcommerceAppJsonLogEvent(
+	header SyslogHeader,
+	processId int,
+	message string,
+) *BigcommerceAppLogEvent {
+	message = strings.TrimPrefix(strings.TrimPrefix(message, "@cee:"), " ")
+
+	if !strings.HasPrefix(message, "{") {
+		return nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(message))
+	decoder.UseNumber()
+
+	var record monologRecord
+
+	if err := decoder.Decode(&record); err != nil {
+		return nil
+	}
+
+	if record.Message == nil || record.LevelName == "" || record.Channel == "" {
+		return nil
+	}
+
+	// Monolog 1 writes datetime as a PHP DateTime object, which doesn't
+	// include the offset, so only Monolog 2's RFC 3339 strings are used.
+	if datetime, ok := record.Datetime.(string); ok {
+		syslogTime, err := time.Parse(time.RFC3339Nano, datetime)
+
+		if err == nil {
+			header.SyslogTime = syslogTime.In(header.SyslogTime.Location())
+		}
+	}
+
+	return &BigcommerceAppLogEvent{
+		SyslogHeader:    header,
+		ProcessId:       processId,
+		Channel:         record.Channel,
+		LogLevel:        record.LevelName,
+		Content:         *record.Message,
+		Args:            record.Context,
+		StoreContext:    newBigcommerceAppStoreContext(record.Extra),
+		OriginalMessage: message,
+	}
+}
+
+// newBigcommerceAppStoreContext picks the store out of a decoded store
+// context, whatever the case of its keys. The store ID can be logged as a
+// number, a string or null.
+func newBigcommerceAppStoreContext(value interface{}) BigcommerceAppStoreContext {
+	fields, ok := value.(map[string]interface{})
+
+	if !ok {
+		return BigcommerceAppStoreContext{}
+	}
+
+	storeContext := BigcommerceAppStoreContext{Fields: fields}
+
+	for key, value := range fields {
+		switch strings.ToLower(key) {
+		case "store_id":
+			switch storeId := value.(type) {
+			case json.Number:
+				id, _ := storeId.Int64()
+				storeContext.StoreId = int(id)
+			case string:
+				storeContext.StoreId, _ = strconv.Atoi(storeId)
+			}
+		case "store_hash":
+			storeContext.StoreHash, _ = value.(string)
+		case "domain":
+			storeContext.Domain, _ = value.(string)
+		}
+	}
+
+	return storeContext
+}
+
+// splitTrailingJson splits the (at most two) space separated JSON values at
+// the end of message from the text before them. PHP's bare NULLs are read as
+// nulls.
+func splitTrailingJson(message string) (string, []interface{}) {
+	for i := 0; i < len(message); i++ {
+		if message[i] != '{' && message[i] != '[' || i > 0 && message[i-1] != ' ' {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(replacePhpNulls(message[i:])))
+		decoder.UseNumber()
+
+		values := []interface{}{}
+
+		for {
+			var value interface{}
+
+			err := decoder.Decode(&value)
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				values = nil
+				break
+			}
+
+			values = append(values, value)
+		}
+
+		if len(values) == 0 || len(values) > 2 {
+			continue
+		}
+
+		// A value that isn't an object or list is part of the text.
+		if !isJsonContainer(values[len(values)-1]) || !isJsonContainer(values[0]) {
+			continue
+		}
+
+		return strings.TrimRight(message[:i], " "), values
+	}
+
+	return message, nil
+}
+
+func isJsonContainer(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+
+	return false
+}
+
+// replacePhpNulls replaces the NULLs outside of strings in PHP's JSON-ish
+// output with nulls.
+func replacePhpNulls(text string) string {
+	var buffer bytes.Buffer
+
+	inString := false
+
+	for i := 0; i < len(text); i++ {
+		switch {
+		case inString && text[i] == '\\' && i+1 < len(text):
+			buffer.WriteByte(text[i])
+			i++
+		case text[i] == '"':
+			inString = !inString
+		case !inString && strings.HasPrefix(text[i:], "NULL"):
+			buffer.WriteString("null")
+			i += 3
+			continue
+		}
+
+		buffer.WriteByte(text[i])
+	}
+
+	return buffer.String()
+}
+
+// lookupJsonValue finds the value at a dotted path in decoded JSON.
+func lookupJsonValue(value interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			child, ok := container[key]
+
+			if !ok {
+				return nil, false
+			}
+
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+
+			if err != nil || index < 0 || index >= len(container) {
+				return nil, false
+			}
+
+			value = container[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// formatJsonValue formats decoded JSON for display, with strings unquoted.
+func formatJsonValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	}
+
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(encoded)
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := []string{}
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBigcommerceAppStoreContext(t *testing.T) {
+	for _, message := range []string{
+		`bigcommerce_app[1234]: BigcommerceApp.ERROR: Payment failed {"order_id":55} ` +
+			`{"store_id":1001,"store_hash":"abc123","domain":"shop.example.com"}`,
+		`bigcommerce_app[1234]: BigcommerceApp.ERROR: Payment failed {"order_id":55} ` +
+			`{"Store_ID":"1001","STORE_HASH":"abc123","Domain":"shop.example.com"}`,
+	} {
+		event, ok := parseTestLine(t, 0, message).(*BigcommerceAppLogEvent)
+
+		if !ok {
+			t.Fatalf("%q isn't a bigcommerce-app event", message)
+		}
+
+		for field, want := range map[string]string{
+			"level":         "ERROR",
+			"store_id":      "1001",
+			"store_hash":    "abc123",
+			"domain":        "shop.example.com",
+			"args.order_id": "55",
+		} {
+			if value, _ := event.GetField(field); value != want {
+				t.Errorf("%q: %s = %q, want %q", message, field, value, want)
+			}
+		}
+	}
+}
+
+func TestBigcommerceAppJsonRecord(t *testing.T) {
+	message := `bigcommerce_app: @cee: {"message":"Job done","context":{"job_id":7},` +
+		`"level":200,"level_name":"INFO","channel":"jobs",` +
+		`"datetime":"2026-10-17T10:00:05+00:00","extra":{"store_id":1001}}`
+
+	event, ok := parseTestLine(t, 0, message).(*BigcommerceAppLogEvent)
+
+	if !ok {
+		t.Fatalf("%q isn't a bigcommerce-app event", message)
+	}
+
+	for field, want := range map[string]string{
+		"channel":     "jobs",
+		"level":       "INFO",
+		"store_id":    "1001",
+		"args.job_id": "7",
+	} {
+		if value, _ := event.GetField(field); value != want {
+			t.Errorf("%s = %q, want %q", field, value, want)
+		}
+	}
+
+	if !event.SyslogTime.Equal(testTime.Add(5 * time.Second)) {
+		t.Errorf("SyslogTime = %s, want the record's datetime", event.SyslogTime)
+	}
+}
+
+// TestBigcommerceAppOtherJsonRecord checks that Monolog records logged by
+// other apps aren't taken for the app's.
+func TestBigcommerceAppOtherJsonRecord(t *testing.T) {
+	message := `billing[99]: {"message":"Invoice sent","context":{},` +
+		`"level":200,"level_name":"INFO","channel":"billing","extra":{}}`
+
+	if event, ok := parseTestLine(t, 0, message).(*BigcommerceAppLogEvent); ok {
+		t.Errorf("%q parsed as a bigcommerce-app event: %+v", message, event)
+	}
+}
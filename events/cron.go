@@ -0,0 +1,401 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+var cronRegexp = regexp.MustCompile(
+	"^(?P<tag>CRON|cron|crond|/USR/SBIN/CRON|/usr/sbin/cron)" +
+		"(?:\\[(?P<pid>[0-9]{1,})\\])?: (?P<content>.*)$",
+)
+var cronSessionRegexp = regexp.MustCompile(
+	"^pam_unix\\(cron:session\\): session (?:opened|closed) for user " +
+		"(?P<user>[^ (]+)",
+)
+var cronKeywordRegexp = regexp.MustCompile(
+	"^\\((?P<user>[^)]*)\\) (?P<keyword>[A-Za-z]+) \\((?P<details>.*)\\)$",
+)
+var cronExitStatusRegexp = regexp.MustCompile("failed with exit status (?P<status>[0-9]{1,})")
+var cronGrandchildRegexp = regexp.MustCompile("^grandchild #(?P<pid>[0-9]{1,}) ")
+
+// CronLogEvent is a line logged by cron. Lines look like
+// "(user) KEYWORD (details)", and Kind is the keyword in lower case, e.g.,
+// "cmd" when a job is started, "error" when it fails or "mail" when its output
+// is mailed. The PAM session lines cron logs around each job have the Kind
+// "session". The Assembler links the lines about a job to its Run.
+type CronLogEvent struct {
+	SyslogHeader
+	Tag     string
+	Pid     int
+	User    string
+	Kind    string
+	Command string
+	Content string
+	Run     *CronRun
+}
+
+// cronRunAge is how long a run (or a session waiting for its job) is
+// remembered after the last line about it.
+const cronRunAge = 24 * time.Hour
+
+// CronRun is one run of a cron job. cron starts each job in a grandchild
+// process, so the CMD line is logged with the job's PID and the lines about
+// how it went with its parent's, which is ParentPid.
+type CronRun struct {
+	Pid        int
+	ParentPid  int
+	User       string
+	Command    string
+	Failed     bool
+	ExitStatus int
+	Output     []string
+	Finished   bool
+
+	last time.Time // log time of the latest line about the run
+}
+
+func (e *CronLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	if e.Kind == "error" {
+		background = ct.Red
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("cron  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s-%s  ", e.User, e.Kind)
+	ct.ChangeColor(ct.None, false, background, false)
+
+	if e.Kind != "cmd" && e.Run != nil {
+		fmt.Printf("%s: %s\n", e.Run.Command, e.Content)
+	} else {
+		fmt.Printf("%s\n", e.Content)
+	}
+
+	ct.ResetColor()
+}
+
+// PrintRunLine prints a line describing how the run the event started went.
+func (e *CronLogEvent) PrintRunLine(index int) {
+	background := ct.None
+	status := "ok"
+
+	if e.Run.Failed {
+		background = ct.Red
+		status = fmt.Sprintf("failed with exit status %d", e.Run.ExitStatus)
+	} else if !e.Run.Finished {
+		status = "running"
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s-%d  ", e.Run.User, e.Run.Pid)
+	ct.ChangeColor(ct.None, false, background, false)
+	fmt.Printf("%s\n", status)
+	ct.ResetColor()
+}
+
+func (e *CronLogEvent) PrintFull() {
+	fmt.Printf("\n---------- CRON LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Tag:\t%s\n", e.Tag)
+	fmt.Fprintf(writer, "Pid:\t%d\n", e.Pid)
+	fmt.Fprintf(writer, "User:\t%s\n", e.User)
+	fmt.Fprintf(writer, "Kind:\t%s\n", e.Kind)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	writer.Flush()
+
+	if e.Run != nil {
+		ct.ChangeColor(ct.White, true, ct.None, false)
+		fmt.Print("\nRun\n")
+		ct.ResetColor()
+
+		fmt.Fprintf(writer, "Command:\t%s\n", e.Run.Command)
+		fmt.Fprintf(writer, "User:\t%s\n", e.Run.User)
+		fmt.Fprintf(writer, "Pid:\t%d\n", e.Run.Pid)
+		fmt.Fprintf(writer, "ParentPid:\t%d\n", e.Run.ParentPid)
+		fmt.Fprintf(writer, "Failed:\t%t\n", e.Run.Failed)
+
+		if e.Run.Failed {
+			fmt.Fprintf(writer, "ExitStatus:\t%d\n", e.Run.ExitStatus)
+		}
+
+		for _, output := range e.Run.Output {
+			fmt.Fprintf(writer, "Output:\t%s\n", output)
+		}
+
+		writer.Flush()
+	}
+
+	fmt.Printf("---------- CRON LOG EVENT ----------\n\n")
+}
+
+func (e *CronLogEvent) Summary() string {
+	return "cron-" + e.Kind
+}
+
+func (e *CronLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	if e.Kind == "error" {
+		return false
+	}
+
+	for _, kind := range settings_.GetCronSuppressKinds() {
+		if e.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetField returns the user and kind of the event, and the command of the run
+// it belongs to.
+func (e *CronLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "user":
+		return e.User, true
+	case "kind":
+		return e.Kind, true
+	case "command":
+		if e.Run != nil {
+			return e.Run.Command, true
+		}
+
+		return e.Command, true
+	}
+
+	return "", false
+}
+
+// add records what the event says about the run.
+func (r *CronRun) add(event *CronLogEvent) {
+	switch event.Kind {
+	case "error":
+		if matches := cronExitStatusRegexp.FindStringSubmatch(event.Content); matches != nil {
+			r.Failed = true
+			r.ExitStatus, _ = strconv.Atoi(matches[1])
+		}
+
+		r.Output = append(r.Output, event.Content)
+	case "session":
+		if strings.Contains(event.Content, "session closed") {
+			r.Finished = true
+		}
+	case "end":
+		r.Finished = true
+	default:
+		r.Output = append(r.Output, event.Content)
+	}
+}
+
+// grandchildPid returns the PID of the job an error line is about, e.g., 1235
+// for "(CRON) error (grandchild #1235 failed with exit status 1)".
+func (e *CronLogEvent) grandchildPid() int {
+	if matches := cronGrandchildRegexp.FindStringSubmatch(e.Content); matches != nil {
+		pid, _ := strconv.Atoi(matches[1])
+		return pid
+	}
+
+	return 0
+}
+
+func (r *CronRun) stale(newest time.Time) bool {
+	return newest.Sub(r.last) > cronRunAge
+}
+
+// cronSessions holds the cron processes that have opened a session for a
+// user but haven't logged their job's CMD line yet, oldest first.
+type cronSessions struct {
+	sessions []cronSession
+}
+
+type cronSession struct {
+	pid    int
+	opened time.Time
+}
+
+// stale forgets the sessions that have waited too long for their job, and
+// reports whether none are left.
+func (s *cronSessions) stale(newest time.Time) bool {
+	for len(s.sessions) > 0 && newest.Sub(s.sessions[0].opened) > cronRunAge {
+		s.sessions = s.sessions[1:]
+	}
+
+	return len(s.sessions) == 0
+}
+
+// assemble links a cron line to the run of the job it is about. A job's CMD
+// line is logged by the job itself, and the other lines by its parent, which
+// opens a session for the job's user just before starting it. So a run is
+// matched up with the oldest session still waiting for a job of its user.
+func (e *CronLogEvent) assemble(a *Assembler) []LogEventInterface {
+	e.link(a)
+
+	return []LogEventInterface{e}
+}
+
+func (e *CronLogEvent) link(a *Assembler) {
+	prefix := "cron " + e.Source + " "
+	sessionKey := prefix + "sessions " + e.User
+	waiting, _ := a.links[sessionKey].(*cronSessions)
+
+	if waiting == nil {
+		waiting = &cronSessions{}
+	}
+
+	if e.Kind == "cmd" {
+		run := &CronRun{
+			Pid:     e.Pid,
+			User:    e.User,
+			Command: e.Command,
+			last:    e.SyslogTime,
+		}
+		a.links[prefix+strconv.Itoa(run.Pid)] = run
+
+		if len(waiting.sessions) > 0 {
+			run.ParentPid = waiting.sessions[0].pid
+			a.links[prefix+strconv.Itoa(run.ParentPid)] = run
+			waiting.sessions = waiting.sessions[1:]
+
+			if len(waiting.sessions) == 0 {
+				delete(a.links, sessionKey)
+			}
+		}
+
+		e.Run = run
+
+		return
+	}
+
+	if e.Kind == "session" && strings.Contains(e.Content, "session opened") {
+		waiting.sessions = append(
+			waiting.sessions,
+			cronSession{pid: e.Pid, opened: e.SyslogTime},
+		)
+		a.links[sessionKey] = waiting
+
+		return
+	}
+
+	var run *CronRun
+	ok := false
+
+	// The parent names the job's PID when it fails, which is more reliable
+	// than matching up the parent with a session, and also finds runs that
+	// weren't matched up.
+	if pid := e.grandchildPid(); pid != 0 {
+		if run, ok = a.links[prefix+strconv.Itoa(pid)].(*CronRun); ok && run.ParentPid == 0 {
+			run.ParentPid = e.Pid
+			a.links[prefix+strconv.Itoa(run.ParentPid)] = run
+		}
+	}
+
+	if !ok {
+		run, ok = a.links[prefix+strconv.Itoa(e.Pid)].(*CronRun)
+	}
+
+	if !ok {
+		// A session that closes without starting a job isn't waiting for
+		// one any more.
+		if e.Kind == "session" {
+			for i, session := range waiting.sessions {
+				if session.pid == e.Pid {
+					waiting.sessions = append(waiting.sessions[:i], waiting.sessions[i+1:]...)
+					break
+				}
+			}
+
+			if len(waiting.sessions) == 0 {
+				delete(a.links, sessionKey)
+			}
+		}
+
+		return
+	}
+
+	run.add(e)
+	run.last = e.SyslogTime
+	e.Run = run
+
+	if run.Finished {
+		delete(a.links, prefix+strconv.Itoa(run.Pid))
+		delete(a.links, prefix+strconv.Itoa(run.ParentPid))
+	}
+}
+
+func init() {
+	RegisterParser(Parser{
+		Name:     "cron",
+		Priority: 41,
+		Match:    NewCronLogEvent,
+	})
+}
+
+// NewCronLogEvent parses lines from processes tagged CRON or cron (including
+// the /USR/SBIN/CRON of older systems).
+func NewCronLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	matches := cronRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return nil
+	}
+
+	event := &CronLogEvent{
+		SyslogHeader: header,
+		Tag:          matches[1],
+		Content:      matches[3],
+	}
+
+	event.Pid, _ = strconv.Atoi(matches[2])
+
+	if matches = cronSessionRegexp.FindStringSubmatch(event.Content); matches != nil {
+		event.User = matches[1]
+		event.Kind = "session"
+
+		return event
+	}
+
+	if matches = cronKeywordRegexp.FindStringSubmatch(event.Content); matches != nil {
+		event.User = matches[1]
+		event.Kind = strings.ToLower(matches[2])
+		event.Content = matches[3]
+
+		if event.Kind == "cmd" {
+			event.Command = matches[3]
+		}
+
+		return event
+	}
+
+	event.Kind = "other"
+
+	return event
+}
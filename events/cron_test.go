@@ -0,0 +1,134 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message string
+		user    string
+		kind    string
+		content string
+	}{
+		{
+			"CRON[1235]: (www-data) CMD (php /var/www/cron.php)",
+			"www-data", "cmd", "php /var/www/cron.php",
+		},
+		{
+			"CRON[1234]: pam_unix(cron:session): session opened for user www-data(uid=33) by (uid=0)",
+			"www-data", "session", "pam_unix(cron:session): session opened for user www-data(uid=33) by (uid=0)",
+		},
+		{
+			"CRON[1234]: pam_unix(cron:session): session closed for user www-data",
+			"www-data", "session", "pam_unix(cron:session): session closed for user www-data",
+		},
+		{
+			"CRON[1234]: (CRON) error (grandchild #1235 failed with exit status 1)",
+			"CRON", "error", "grandchild #1235 failed with exit status 1",
+		},
+		{
+			"cron[800]: (CRON) INFO (pidfile fd = 3)",
+			"CRON", "info", "pidfile fd = 3",
+		},
+		{
+			"crond[900]: something cron-ish",
+			"", "other", "something cron-ish",
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*CronLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't a cron event", test.message)
+			continue
+		}
+
+		if event.User != test.user || event.Kind != test.kind || event.Content != test.content {
+			t.Errorf("%q parsed as %+v", test.message, event)
+		}
+	}
+}
+
+func TestCronRun(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	var cmd *CronLogEvent
+
+	for i, line := range []string{
+		"CRON[1234]: pam_unix(cron:session): session opened for user www-data(uid=33) by (uid=0)",
+		"CRON[1235]: (www-data) CMD (php /var/www/cron.php)",
+		"CRON[1234]: (CRON) error (grandchild #1235 failed with exit status 2)",
+		"CRON[1234]: pam_unix(cron:session): session closed for user www-data",
+	} {
+		event := assembler.Add(parseTestLine(t, float64(i), line))[0].(*CronLogEvent)
+
+		if event.Kind == "cmd" {
+			cmd = event
+		} else if i > 1 && event.Run != cmd.Run {
+			t.Fatalf("%q isn't linked to the run", line)
+		}
+	}
+
+	run := cmd.Run
+
+	if run.ParentPid != 1234 || !run.Failed || run.ExitStatus != 2 || !run.Finished {
+		t.Errorf("run = %+v", run)
+	}
+
+	if countLinks(assembler, &CronRun{}) != 0 {
+		t.Errorf("finished run is still remembered")
+	}
+}
+
+// TestCronRunGrandchild checks that a line naming the job's PID is linked to
+// that job even when the parent was matched up with the wrong session.
+func TestCronRunGrandchild(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+	runs := make(map[int]*CronRun)
+
+	for i, line := range []string{
+		"CRON[1000]: pam_unix(cron:session): session opened for user www-data(uid=33) by (uid=0)",
+		"CRON[2000]: pam_unix(cron:session): session opened for user www-data(uid=33) by (uid=0)",
+		"CRON[2001]: (www-data) CMD (php /var/www/second.php)",
+		"CRON[1001]: (www-data) CMD (php /var/www/first.php)",
+		"CRON[2000]: (CRON) error (grandchild #2001 failed with exit status 1)",
+	} {
+		event := assembler.Add(parseTestLine(t, float64(i), line))[0].(*CronLogEvent)
+
+		if event.Kind == "cmd" {
+			runs[event.Pid] = event.Run
+		} else if event.Kind == "error" && event.Run != runs[2001] {
+			t.Errorf("error linked to %+v, want the run of PID 2001", event.Run)
+		}
+	}
+
+	if !runs[2001].Failed || runs[1001].Failed {
+		t.Errorf("wrong run failed: %+v, %+v", runs[2001], runs[1001])
+	}
+}
+
+// TestCronRunEviction checks that runs that never finish and sessions that
+// never start a job are forgotten after cronRunAge.
+func TestCronRunEviction(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	assembler.Add(parseTestLine(t, 0, "CRON[1234]: pam_unix(cron:session): session opened for user root(uid=0) by (uid=0)"))
+	assembler.Add(parseTestLine(t, 1, "CRON[1234]: pam_unix(cron:session): session opened for user www-data(uid=33) by (uid=0)"))
+	assembler.Add(parseTestLine(t, 2, "CRON[1235]: (www-data) CMD (php /var/www/cron.php)"))
+
+	if countLinks(assembler, &CronRun{}) != 2 || countLinks(assembler, &cronSessions{}) != 1 {
+		t.Fatalf("%d runs and %d session queues remembered", countLinks(assembler, &CronRun{}), countLinks(assembler, &cronSessions{}))
+	}
+
+	later := cronRunAge.Seconds() + 7200
+	assembler.Add(parseTestLine(t, later, "cron[800]: (CRON) INFO (Running @reboot jobs)"))
+
+	if countLinks(assembler, &CronRun{}) != 0 || countLinks(assembler, &cronSessions{}) != 0 {
+		t.Errorf(
+			"%d runs and %d session queues remembered, want them evicted",
+			countLinks(assembler, &CronRun{}),
+			countLinks(assembler, &cronSessions{}),
+		)
+	}
+}
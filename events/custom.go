@@ -0,0 +1,263 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"text/tabwriter"
+	"text/template"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+// FieldInterface is implemented by events with named fields that can be
+// filtered and grouped on.
+type FieldInterface interface {
+	GetField(name string) (string, bool)
+}
+
+// CustomLogEvent is an event of one of the types defined in the CustomEvents
+// section of the config.
+type CustomLogEvent struct {
+	SyslogHeader
+	Name            string
+	Level           string
+	Content         string
+	FieldNames      []string
+	Fields          map[string]string
+	OriginalMessage string
+}
+
+func (e *CustomLogEvent) PrintLine(index int) {
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, ct.None, false)
+	fmt.Printf("%s  ", e.Name)
+	ct.ChangeColor(ct.Cyan, false, ct.None, false)
+
+	if e.Level != "" {
+		fmt.Printf("%s  ", e.Level)
+	}
+
+	ct.ResetColor()
+	fmt.Printf("%s\n", e.Content)
+}
+
+func (e *CustomLogEvent) PrintFull() {
+	fmt.Printf("\n---------- %s EVENT ----------\n", e.Name)
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Level:\t%s\n", e.Level)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	for _, name := range e.FieldNames {
+		fmt.Fprintf(writer, "%s:\t%s\n", name, e.Fields[name])
+	}
+
+	fmt.Fprintf(writer, "Original:\t%s\n", e.OriginalMessage)
+
+	writer.Flush()
+
+	fmt.Printf("---------- %s EVENT ----------\n\n", e.Name)
+}
+
+func (e *CustomLogEvent) Summary() string {
+	if e.Level == "" {
+		return e.Name
+	}
+
+	return e.Name + "-" + e.Level
+}
+
+func (e *CustomLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	for _, level := range settings_.GetCustomEventSuppressLogLevels(e.Name) {
+		if e.Level == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *CustomLogEvent) GetField(name string) (string, bool) {
+	value, ok := e.Fields[name]
+
+	return value, ok
+}
+
+var customParserNames []string
+
+// RegisterCustomParsers replaces the parsers for custom event types with ones
+// for the types defined in customEvents. If any of the types is invalid, the
+// error is returned and the parsers are left as they were.
+func RegisterCustomParsers(
+	customEvents []settings.CustomEventSettings,
+) error {
+	custom := make(map[string]bool)
+
+	for _, name := range customParserNames {
+		custom[name] = true
+	}
+
+	newParsers := []Parser{}
+	newNames := []string{}
+	taken := make(map[string]bool)
+
+	for _, customEvent := range customEvents {
+		parsersMutex.RLock()
+		_, exists := parsers[customEvent.Name]
+		parsersMutex.RUnlock()
+
+		if exists && !custom[customEvent.Name] || taken[customEvent.Name] {
+			return fmt.Errorf(
+				"custom event %s: name is already taken by another parser",
+				customEvent.Name,
+			)
+		}
+
+		parser, err := newCustomParser(customEvent)
+
+		if err != nil {
+			return fmt.Errorf("custom event %s: %s", customEvent.Name, err)
+		}
+
+		taken[parser.Name] = true
+		newParsers = append(newParsers, parser)
+		newNames = append(newNames, parser.Name)
+	}
+
+	replaceParsers(customParserNames, newParsers)
+	customParserNames = newNames
+
+	return nil
+}
+
+func newCustomParser(customEvent settings.CustomEventSettings) (Parser, error) {
+	re, err := regexp.Compile(customEvent.Regex)
+
+	if err != nil {
+		return Parser{}, err
+	}
+
+	groupNames := make(map[string]bool)
+
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			groupNames[name] = true
+		}
+	}
+
+	if customEvent.LevelGroup != "" && !groupNames[customEvent.LevelGroup] {
+		return Parser{}, fmt.Errorf(
+			"level group %s is not a named group of the regex",
+			customEvent.LevelGroup,
+		)
+	}
+
+	for _, name := range customEvent.Fields {
+		if !groupNames[name] {
+			return Parser{}, fmt.Errorf(
+				"field %s is not a named group of the regex",
+				name,
+			)
+		}
+	}
+
+	// Templates may only refer to the named groups, which is checked by
+	// running the template once on empty values.
+	summary, err := template.New(customEvent.Name).
+		Option("missingkey=error").
+		Parse(customEvent.Template)
+
+	if err != nil {
+		return Parser{}, err
+	}
+
+	empty := make(map[string]string)
+
+	for name := range groupNames {
+		empty[name] = ""
+	}
+
+	if err = summary.Execute(ioutil.Discard, empty); err != nil {
+		return Parser{}, err
+	}
+
+	fieldNames := customEvent.Fields
+
+	// Without a list of fields every named group other than the level is one.
+	if len(fieldNames) == 0 {
+		for _, name := range re.SubexpNames() {
+			if name != "" && name != customEvent.LevelGroup {
+				fieldNames = append(fieldNames, name)
+			}
+		}
+	}
+
+	priority := customEvent.Priority
+
+	// Custom event types are usually more specific than the process and
+	// generic ones, so they go ahead of them by default.
+	if priority == 0 {
+		priority = 40
+	}
+
+	return Parser{
+		Name:     customEvent.Name,
+		Priority: priority,
+		Match: func(header SyslogHeader, message string) LogEventInterface {
+			matches := re.FindStringSubmatch(message)
+
+			if matches == nil {
+				return nil
+			}
+
+			groups := make(map[string]string)
+
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					groups[name] = matches[i]
+				}
+			}
+
+			fields := make(map[string]string)
+
+			for _, name := range fieldNames {
+				fields[name] = groups[name]
+			}
+
+			// The raw message is shown if the template fails anyway.
+			content := message
+
+			if customEvent.Template != "" {
+				var buffer bytes.Buffer
+
+				if err := summary.Execute(&buffer, groups); err == nil {
+					content = buffer.String()
+				}
+			}
+
+			return &CustomLogEvent{
+				SyslogHeader:    header,
+				Name:            customEvent.Name,
+				Level:           groups[customEvent.LevelGroup],
+				Content:         content,
+				FieldNames:      fieldNames,
+				Fields:          fields,
+				OriginalMessage: message,
+			}
+		},
+	}, nil
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"testing"
+
+	settings "github.com/lovek323/bclog/settings"
+)
+
+func testCustomEvent() settings.CustomEventSettings {
+	return settings.CustomEventSettings{
+		Name:       "indexer",
+		Regex:      "^indexer\\[[0-9]+\\]: (?P<level>[A-Z]+) job=(?P<job>[0-9]+) (?P<message>.*)$",
+		LevelGroup: "level",
+		Fields:     []string{"job", "message"},
+		Template:   "job {{.job}}: {{.message}}",
+	}
+}
+
+func registerTestCustomParsers(t *testing.T, customEvents ...settings.CustomEventSettings) error {
+	t.Cleanup(func() { RegisterCustomParsers(nil) })
+
+	return RegisterCustomParsers(customEvents)
+}
+
+func TestCustomLogEvent(t *testing.T) {
+	if err := registerTestCustomParsers(t, testCustomEvent()); err != nil {
+		t.Fatalf("RegisterCustomParsers() = %s", err)
+	}
+
+	event, ok := parseTestLine(t, 0, "indexer[812]: ERROR job=42 product feed timed out").(*CustomLogEvent)
+
+	if !ok {
+		t.Fatal("the line isn't a custom event")
+	}
+
+	if event.Summary() != "indexer-ERROR" || event.Content != "job 42: product feed timed out" {
+		t.Errorf("event = %+v", event)
+	}
+
+	for name, want := range map[string]string{"job": "42", "message": "product feed timed out"} {
+		if value, ok := event.GetField(name); !ok || value != want {
+			t.Errorf("GetField(%q) = %q, %t, want %q", name, value, ok, want)
+		}
+	}
+
+	if _, ok := event.GetField("level"); ok {
+		t.Error("GetField(\"level\") found a group that isn't listed in Fields")
+	}
+}
+
+func TestRegisterCustomParsersInvalid(t *testing.T) {
+	unknownLevel := testCustomEvent()
+	unknownLevel.LevelGroup = "severity"
+
+	unknownField := testCustomEvent()
+	unknownField.Fields = []string{"job", "queue"}
+
+	unknownTemplateGroup := testCustomEvent()
+	unknownTemplateGroup.Template = "{{.queue}}: {{.message}}"
+
+	builtIn := testCustomEvent()
+	builtIn.Name = "nginx"
+
+	for name, customEvent := range map[string]settings.CustomEventSettings{
+		"unknown level group":    unknownLevel,
+		"unknown field":          unknownField,
+		"unknown template group": unknownTemplateGroup,
+		"built-in name":          builtIn,
+	} {
+		if err := registerTestCustomParsers(t, customEvent); err == nil {
+			t.Errorf("%s: RegisterCustomParsers() = nil, want an error", name)
+		}
+	}
+}
+
+// TestRegisterCustomParsersKeepsParsers checks that a config with a mistake
+// in one custom event type leaves the parsers that were registered before.
+func TestRegisterCustomParsersKeepsParsers(t *testing.T) {
+	if err := registerTestCustomParsers(t, testCustomEvent()); err != nil {
+		t.Fatalf("RegisterCustomParsers() = %s", err)
+	}
+
+	other := testCustomEvent()
+	other.Name = "exporter"
+	other.Regex = "^exporter: (?P<message>.*)$"
+	other.Template = "{{.message}}"
+	other.Fields = nil
+	other.LevelGroup = ""
+
+	invalid := testCustomEvent()
+	invalid.Name = "importer"
+	invalid.LevelGroup = "severity"
+
+	if err := RegisterCustomParsers([]settings.CustomEventSettings{other, invalid}); err == nil {
+		t.Fatal("RegisterCustomParsers() = nil, want an error")
+	}
+
+	if _, ok := parseTestLine(t, 0, "indexer[812]: INFO job=43 done").(*CustomLogEvent); !ok {
+		t.Error("the indexer parser was removed")
+	}
+
+	if _, ok := parseTestLine(t, 0, "exporter: done").(*CustomLogEvent); ok {
+		t.Error("the exporter parser was added")
+	}
+}
@@ -11,6 +11,75 @@ type LogEventInterface interface {
     PrintFull()
 
     GetSyslogTime()                      time.Time
+    GetSource()                          string
+    GetFacility()                        int
+    GetSeverity()                        int
     Summary()                            string
     Suppress(settings.SettingsInterface) bool
 }
+
+// SyslogHeader holds what syslog itself records about every message, whatever
+// the message turns out to be. Events embed it.
+type SyslogHeader struct {
+    SyslogTime time.Time
+
+    // Source is the label of the configured source the line came from, or
+    // the hostname syslog recorded if the source has no label.
+    Source     string
+
+    // Facility and Severity come from the priority at the start of the
+    // message, and are -1 for messages that don't have one.
+    Facility   int
+    Severity   int
+
+    // StructuredData maps the SD-ID of each RFC 5424 structured data element
+    // to its parameters.
+    StructuredData map[string]map[string]string
+}
+
+var facilityNames = []string{
+    "kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+    "uucp", "cron", "authpriv", "ftp", "ntp", "security", "console",
+    "solaris-cron", "local0", "local1", "local2", "local3", "local4",
+    "local5", "local6", "local7",
+}
+
+var severityNames = []string{
+    "emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+func (h *SyslogHeader) GetSyslogTime() time.Time {
+    return h.SyslogTime
+}
+
+func (h *SyslogHeader) GetSource() string {
+    return h.Source
+}
+
+func (h *SyslogHeader) GetFacility() int {
+    return h.Facility
+}
+
+func (h *SyslogHeader) GetSeverity() int {
+    return h.Severity
+}
+
+// FacilityName returns the name syslog uses for a facility, e.g., "cron", or
+// an empty string if it isn't known.
+func FacilityName(facility int) string {
+    if facility < 0 || facility >= len(facilityNames) {
+        return ""
+    }
+
+    return facilityNames[facility]
+}
+
+// SeverityName returns the name syslog uses for a severity, e.g., "err", or
+// an empty string if it isn't known.
+func SeverityName(severity int) string {
+    if severity < 0 || severity >= len(severityNames) {
+        return ""
+    }
+
+    return severityNames[severity]
+}
@@ -3,21 +3,25 @@ package events
 import (
 	"fmt"
 	"regexp"
-	"time"
 
 	ct "github.com/daviddengcn/go-colortext"
 	settings "github.com/lovek323/bclog/settings"
 )
 
+var genericRegexp = regexp.MustCompile(
+	"^(?P<name>.*?): (?P<message>.*)$",
+)
+
 type GenericLogEvent struct {
-	SyslogTime time.Time
-	Name       string
-	Content    string
+	SyslogHeader
+	Name    string
+	Content string
 }
 
 func (e *GenericLogEvent) PrintLine(index int) {
 	fmt.Printf("[%d]  ", index)
 	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
 	ct.ChangeColor(ct.Yellow, false, ct.None, false)
 	fmt.Print("generic  ")
 	ct.ChangeColor(ct.Cyan, false, ct.None, false)
@@ -43,20 +47,19 @@ func (e *GenericLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
 	return false
 }
 
-func (e *GenericLogEvent) GetSyslogTime() time.Time {
-	return e.SyslogTime
+func init() {
+	RegisterParser(Parser{
+		Name:     "generic",
+		Priority: 100,
+		Match:    NewGenericLogEvent,
+	})
 }
 
 func NewGenericLogEvent(
-	syslogTime time.Time,
-	source string,
+	header SyslogHeader,
 	message string,
 ) LogEventInterface {
-	re := regexp.MustCompile(
-		"^(?P<name>.*?): (?P<message>.*)$",
-	)
-
-	matches := re.FindStringSubmatch(message)
+	matches := genericRegexp.FindStringSubmatch(message)
 
 	if matches == nil {
 		return nil
@@ -66,8 +69,8 @@ func NewGenericLogEvent(
 	content := matches[2]
 
 	return &GenericLogEvent{
-		SyslogTime: syslogTime,
-		Name:       name,
-		Content:    content,
+		SyslogHeader: header,
+		Name:         name,
+		Content:      content,
 	}
 }
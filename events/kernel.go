@@ -0,0 +1,225 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+var kernelRegexp = regexp.MustCompile(
+	"^kernel: (?:\\[ *(?P<uptime>[0-9]+\\.[0-9]+)\\] )?(?P<content>.*)$",
+)
+var kernelOomRegexp = regexp.MustCompile("^(?P<process>.+?) invoked oom-killer: ")
+var kernelOomKillRegexp = regexp.MustCompile(
+	"Kill(?:ed)? process (?P<pid>[0-9]{1,}) \\((?P<process>[^)]*)\\)",
+)
+var kernelTotalVmRegexp = regexp.MustCompile("total-vm:(?P<size>[0-9]{1,})kB")
+var kernelRssRegexp = regexp.MustCompile("(?:anon|file|shmem)-rss:(?P<size>[0-9]{1,})kB")
+var kernelSegfaultRegexp = regexp.MustCompile(
+	"^(?:traps: )?(?P<process>.+?)\\[(?P<pid>[0-9]{1,})\\]:? " +
+		"(?:segfault at|general protection|trap invalid opcode)",
+)
+var kernelDiskErrorRegexp = regexp.MustCompile(
+	"I/O error|EXT[234]-fs error|XFS \\([^)]*\\):.* error|" +
+		"^ata[0-9.]+: (?:failed command|exception|error)|" +
+		"Remounting filesystem read-only|critical medium error",
+)
+var kernelDeviceRegexp = regexp.MustCompile(
+	"(?:dev |device |\\()(?P<device>(?:sd|vd|xvd|hd|nvme|md|dm-)[a-z0-9]*)|" +
+		"^(?P<ata>ata[0-9.]+):",
+)
+
+// KernelLogEvent is a message from the kernel. Kind says what the message is
+// about:
+//
+//	oom         a process ran the machine out of memory and invoked the
+//	            OOM killer
+//	oom-kill    the OOM killer killed a process (Rss is how much memory it
+//	            freed)
+//	segfault    a process crashed
+//	disk-error  a disk or file system reported an error
+//
+// and is "other" for everything else.
+type KernelLogEvent struct {
+	SyslogHeader
+	Uptime  float64 // seconds since boot, -1 if not logged
+	Kind    string
+	Process string
+	Pid     int
+	TotalVm int // kB
+	Rss     int // kB
+	Device  string
+	Content string
+}
+
+func (e *KernelLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	if e.Kind != "other" {
+		background = ct.Red
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("kernel  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+
+	switch e.Kind {
+	case "oom":
+		fmt.Printf("%s-%s  ", e.Kind, e.Process)
+	case "oom-kill", "segfault":
+		fmt.Printf("%s-%s-%d  ", e.Kind, e.Process, e.Pid)
+	case "disk-error":
+		fmt.Printf("%s-%s  ", e.Kind, e.Device)
+	default:
+		fmt.Printf("%s  ", e.Kind)
+	}
+
+	ct.ChangeColor(ct.None, false, background, false)
+	fmt.Printf("%s\n", e.Content)
+	ct.ResetColor()
+}
+
+func (e *KernelLogEvent) PrintFull() {
+	fmt.Printf("\n---------- KERNEL LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+
+	if e.Uptime >= 0 {
+		fmt.Fprintf(writer, "Uptime:\t%.6fs\n", e.Uptime)
+	}
+
+	fmt.Fprintf(writer, "Kind:\t%s\n", e.Kind)
+	fmt.Fprintf(writer, "Process:\t%s\n", e.Process)
+	fmt.Fprintf(writer, "Pid:\t%d\n", e.Pid)
+
+	if e.Kind == "oom-kill" {
+		fmt.Fprintf(writer, "TotalVm:\t%d kB\n", e.TotalVm)
+		fmt.Fprintf(writer, "Rss:\t%d kB\n", e.Rss)
+	}
+
+	fmt.Fprintf(writer, "Device:\t%s\n", e.Device)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	writer.Flush()
+
+	fmt.Printf("---------- KERNEL LOG EVENT ----------\n\n")
+}
+
+func (e *KernelLogEvent) Summary() string {
+	return "kernel-" + e.Kind
+}
+
+func (e *KernelLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	for _, kind := range settings_.GetKernelSuppressKinds() {
+		if e.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *KernelLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "kind":
+		return e.Kind, true
+	case "process":
+		return e.Process, true
+	case "pid":
+		return strconv.Itoa(e.Pid), true
+	case "device":
+		return e.Device, true
+	}
+
+	return "", false
+}
+
+func init() {
+	RegisterParser(Parser{
+		Name:     "kernel",
+		Priority: 42,
+		Match:    NewKernelLogEvent,
+	})
+}
+
+func NewKernelLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	matches := kernelRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return nil
+	}
+
+	event := &KernelLogEvent{
+		SyslogHeader: header,
+		Uptime:       -1,
+		Kind:         "other",
+		Content:      matches[2],
+	}
+
+	if matches[1] != "" {
+		event.Uptime, _ = strconv.ParseFloat(matches[1], 64)
+	}
+
+	if matches = kernelOomRegexp.FindStringSubmatch(event.Content); matches != nil {
+		event.Kind = "oom"
+		event.Process = matches[1]
+
+		return event
+	}
+
+	if matches = kernelOomKillRegexp.FindStringSubmatch(event.Content); matches != nil {
+		event.Kind = "oom-kill"
+		event.Pid, _ = strconv.Atoi(matches[1])
+		event.Process = matches[2]
+
+		if matches = kernelTotalVmRegexp.FindStringSubmatch(event.Content); matches != nil {
+			event.TotalVm, _ = strconv.Atoi(matches[1])
+		}
+
+		// The memory freed is the process's anonymous, file and shared
+		// memory.
+		for _, rss := range kernelRssRegexp.FindAllStringSubmatch(event.Content, -1) {
+			size, _ := strconv.Atoi(rss[1])
+			event.Rss += size
+		}
+
+		return event
+	}
+
+	if matches = kernelSegfaultRegexp.FindStringSubmatch(event.Content); matches != nil {
+		event.Kind = "segfault"
+		event.Process = matches[1]
+		event.Pid, _ = strconv.Atoi(matches[2])
+
+		return event
+	}
+
+	if kernelDiskErrorRegexp.MatchString(event.Content) {
+		event.Kind = "disk-error"
+
+		if matches = kernelDeviceRegexp.FindStringSubmatch(event.Content); matches != nil {
+			event.Device = matches[1] + matches[2]
+		}
+	}
+
+	return event
+}
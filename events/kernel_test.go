@@ -0,0 +1,71 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKernelLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message string
+		want    KernelLogEvent
+	}{
+		{
+			"kernel: [1234567.890123] php-fpm invoked oom-killer: gfp_mask=0x100cca(GFP_HIGHUSER_MOVABLE), order=0, oom_score_adj=0",
+			KernelLogEvent{Uptime: 1234567.890123, Kind: "oom", Process: "php-fpm"},
+		},
+		{
+			"kernel: [1234567.950000] Out of memory: Killed process 1234 (php-fpm) total-vm:2097152kB, anon-rss:1048576kB, file-rss:2048kB, shmem-rss:1024kB, UID:33 pgtables:4096kB oom_score_adj:0",
+			KernelLogEvent{
+				Uptime:  1234567.95,
+				Kind:    "oom-kill",
+				Process: "php-fpm",
+				Pid:     1234,
+				TotalVm: 2097152,
+				Rss:     1051648,
+			},
+		},
+		{
+			"kernel: php-fpm[4321]: segfault at 0 ip 000055d5c7a1b2c3 sp 00007ffd1c2b3a40 error 4 in php-fpm7.4[55d5c7800000+2a0000]",
+			KernelLogEvent{Uptime: -1, Kind: "segfault", Process: "php-fpm", Pid: 4321},
+		},
+		{
+			"kernel: [ 4242.000001] traps: redis-server[987] general protection fault ip:7f1c2d3e4f50 sp:7ffe5a6b7c80 error:0 in libc-2.31.so[7f1c2d300000+178000]",
+			KernelLogEvent{Uptime: 4242.000001, Kind: "segfault", Process: "redis-server", Pid: 987},
+		},
+		{
+			"kernel: [ 4242.100000] blk_update_request: I/O error, dev sda, sector 123456789 op 0x0:(READ) flags 0x0 phys_seg 1 prio class 0",
+			KernelLogEvent{Uptime: 4242.1, Kind: "disk-error", Device: "sda"},
+		},
+		{
+			"kernel: [ 4242.200000] ata1.00: failed command: READ FPDMA QUEUED",
+			KernelLogEvent{Uptime: 4242.2, Kind: "disk-error", Device: "ata1.00"},
+		},
+		{
+			"kernel: [ 4242.300000] EXT4-fs error (device nvme0n1p1): ext4_find_entry:1455: inode #2: comm nginx: reading directory lblock 0",
+			KernelLogEvent{Uptime: 4242.3, Kind: "disk-error", Device: "nvme0n1p1"},
+		},
+		{
+			"kernel: [    0.000000] Linux version 5.15.0-91-generic (buildd@lcy02-amd64-045)",
+			KernelLogEvent{Kind: "other"},
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*KernelLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't a kernel event", test.message)
+			continue
+		}
+
+		test.want.SyslogHeader = event.SyslogHeader
+		test.want.Content = event.Content
+
+		if !reflect.DeepEqual(*event, test.want) {
+			t.Errorf("%q parsed as %+v, want %+v", test.message, *event, test.want)
+		}
+
+		if summary := event.Summary(); summary != "kernel-"+test.want.Kind {
+			t.Errorf("%q summary = %q", test.message, summary)
+		}
+	}
+}
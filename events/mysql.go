@@ -0,0 +1,431 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+var mysqlRegexp = regexp.MustCompile(
+	"^(?P<tag>[^ :\\[]+)(?:\\[[0-9]{1,}\\])?: (?P<content>.*)$",
+)
+var mysqlErrorLogRegexp = regexp.MustCompile(
+	"^(?:(?:[0-9]{4}-[0-9]{2}-[0-9]{2}[T ][0-9:.]+Z?|[0-9]{6} +[0-9:]{7,8}) )?" +
+		"(?:(?P<threadId>[0-9]{1,}) )?\\[(?P<level>[A-Za-z]+)\\] " +
+		"(?:\\[(?P<code>MY-[0-9]+)\\] )?(?:\\[(?P<subsystem>[A-Za-z]+)\\] )?" +
+		"(?P<content>.*)$",
+)
+var mysqlSlowLogUserRegexp = regexp.MustCompile(
+	"^# User@Host: (?P<user>[^\\[ ]*)\\[[^\\]]*\\] @ " +
+		"(?P<host>[^ ]*) ?\\[(?P<ip>[^\\]]*)\\](?:\\s+Id:\\s+(?P<threadId>[0-9]{1,}))?",
+)
+var mysqlSlowLogStatRegexp = regexp.MustCompile("([A-Za-z_]+): +([0-9.]+)")
+var mysqlSlowLogUseRegexp = regexp.MustCompile("^(?i)use `?([^`;]+)`?;$")
+
+// mysqlFingerprintReplacements are applied in order by MysqlFingerprint.
+var mysqlFingerprintReplacements = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile("'(?:[^'\\\\]|\\\\.|'')*'"), "?"},
+	{regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\""), "?"},
+	{regexp.MustCompile("(?s)/\\*.*?\\*/"), ""},
+	{regexp.MustCompile("(?m)(?:-- |#)[^\\n]*$"), ""},
+	{regexp.MustCompile("\\b0x[0-9a-fA-F]+\\b"), "?"},
+	{regexp.MustCompile("\\b[0-9]+(?:\\.[0-9]+)?(?:[eE][-+]?[0-9]+)?\\b"), "?"},
+	{regexp.MustCompile("([=<>(,]\\s*)-\\?"), "${1}?"},
+	{regexp.MustCompile("\\s+"), " "},
+	{regexp.MustCompile("\\(\\s*\\?(?:\\s*,\\s*\\?)*\\s*\\)"), "(?+)"},
+	{regexp.MustCompile("(?i)\\bvalues\\s*(?:\\(\\?\\+\\)\\s*,?\\s*)+"), "values (?+)"},
+}
+
+// MysqlLogEvent is a message from mysqld's error log.
+type MysqlLogEvent struct {
+	SyslogHeader
+	Tag       string
+	ThreadId  int
+	LogLevel  string
+	Code      string
+	Subsystem string
+	Content   string
+}
+
+func (e *MysqlLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	if e.LogLevel == "ERROR" {
+		background = ct.Red
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("mysql  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s  ", e.LogLevel)
+	ct.ChangeColor(ct.None, false, background, false)
+	fmt.Printf("%s\n", e.Content)
+	ct.ResetColor()
+}
+
+func (e *MysqlLogEvent) PrintFull() {
+	fmt.Printf("\n---------- MYSQL LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Tag:\t%s\n", e.Tag)
+	fmt.Fprintf(writer, "ThreadId:\t%d\n", e.ThreadId)
+	fmt.Fprintf(writer, "LogLevel:\t%s\n", e.LogLevel)
+	fmt.Fprintf(writer, "Code:\t%s\n", e.Code)
+	fmt.Fprintf(writer, "Subsystem:\t%s\n", e.Subsystem)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	writer.Flush()
+
+	fmt.Printf("---------- MYSQL LOG EVENT ----------\n\n")
+}
+
+func (e *MysqlLogEvent) Summary() string {
+	if e.LogLevel == "" {
+		return "mysql"
+	}
+
+	return "mysql-" + strings.ToLower(e.LogLevel)
+}
+
+func (e *MysqlLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	for _, level := range settings_.GetMysqlSuppressLogLevels() {
+		if strings.EqualFold(e.LogLevel, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MysqlSlowQueryLogEvent is an entry of the slow query log. Fingerprint is
+// the query with its literals replaced, so that runs of the same query with
+// different values can be grouped together.
+type MysqlSlowQueryLogEvent struct {
+	SyslogHeader
+	Tag          string
+	User         string
+	Host         string
+	ThreadId     int
+	QueryTime    float64
+	LockTime     float64
+	RowsSent     int
+	RowsExamined int
+	Database     string
+	Query        string
+	Fingerprint  string
+
+	// part is the kind of slow log line the event was parsed from, until
+	// the Assembler has put the entry together.
+	part string
+}
+
+func (e *MysqlSlowQueryLogEvent) PrintLine(index int) {
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, ct.None, false)
+	fmt.Print("mysql-slow-query  ")
+	ct.ChangeColor(ct.Cyan, false, ct.None, false)
+	fmt.Printf("%.3fs-%d  ", e.QueryTime, e.RowsExamined)
+	ct.ResetColor()
+	fmt.Printf("%s\n", e.Fingerprint)
+}
+
+func (e *MysqlSlowQueryLogEvent) PrintFull() {
+	fmt.Printf("\n---------- MYSQL SLOW QUERY LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "User:\t%s\n", e.User)
+	fmt.Fprintf(writer, "Host:\t%s\n", e.Host)
+	fmt.Fprintf(writer, "ThreadId:\t%d\n", e.ThreadId)
+	fmt.Fprintf(writer, "QueryTime:\t%.6fs\n", e.QueryTime)
+	fmt.Fprintf(writer, "LockTime:\t%.6fs\n", e.LockTime)
+	fmt.Fprintf(writer, "RowsSent:\t%d\n", e.RowsSent)
+	fmt.Fprintf(writer, "RowsExamined:\t%d\n", e.RowsExamined)
+	fmt.Fprintf(writer, "Database:\t%s\n", e.Database)
+	fmt.Fprintf(writer, "Fingerprint:\t%s\n", e.Fingerprint)
+
+	writer.Flush()
+
+	fmt.Printf("\n%s\n", e.Query)
+
+	fmt.Printf("---------- MYSQL SLOW QUERY LOG EVENT ----------\n\n")
+}
+
+func (e *MysqlSlowQueryLogEvent) Summary() string {
+	return "mysql-slow-query"
+}
+
+func (e *MysqlSlowQueryLogEvent) Suppress(
+	settings_ settings.SettingsInterface,
+) bool {
+	return false
+}
+
+func (e *MysqlSlowQueryLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "user":
+		return e.User, true
+	case "database":
+		return e.Database, true
+	case "fingerprint":
+		return e.Fingerprint, true
+	}
+
+	return "", false
+}
+
+// addSlowLogLine adds a line of a slow log entry to the entry.
+func (e *MysqlSlowQueryLogEvent) addSlowLogLine(line *MysqlSlowQueryLogEvent) {
+	switch line.part {
+	case "user-host":
+		e.User = line.User
+		e.Host = line.Host
+		e.ThreadId = line.ThreadId
+	case "query-time":
+		e.QueryTime = line.QueryTime
+		e.LockTime = line.LockTime
+		e.RowsSent = line.RowsSent
+		e.RowsExamined = line.RowsExamined
+	case "use":
+		e.Database = line.Database
+	case "sql":
+		if e.Query != "" {
+			e.Query += "\n"
+		}
+
+		e.Query += line.Query
+		e.Fingerprint = MysqlFingerprint(e.Query)
+	}
+}
+
+// startsSlowLogEntry reports whether the line is the first of an entry, given
+// the entry that is being put together.
+func (e *MysqlSlowQueryLogEvent) startsSlowLogEntry(
+	entry *MysqlSlowQueryLogEvent,
+) bool {
+	switch e.part {
+	case "time":
+		return true
+	case "user-host", "query-time":
+		return entry.Query != ""
+	}
+
+	return false
+}
+
+// assemble holds slow log entries back until all of their lines have been
+// added.
+func (e *MysqlSlowQueryLogEvent) assemble(a *Assembler) []LogEventInterface {
+	key := "mysql " + e.Source + " " + e.Tag
+	pending, ok := a.pending[key]
+
+	if ok && e.SyslogTime.Sub(pending.last) > a.Window {
+		ok = false
+	}
+
+	if !ok || e.startsSlowLogEntry(pending.event.(*MysqlSlowQueryLogEvent)) {
+		complete := a.release(key)
+		a.hold(key, e)
+
+		return complete
+	}
+
+	pending.event.(*MysqlSlowQueryLogEvent).addSlowLogLine(e)
+	pending.last = e.SyslogTime
+
+	return nil
+}
+
+func init() {
+	RegisterParser(Parser{
+		Name:     "mysql",
+		Priority: 36,
+		Match:    NewMysqlLogEvent,
+	})
+}
+
+// NewMysqlLogEvent parses the error log messages mysqld sends to syslog, and
+// the lines of a slow query log that is forwarded to syslog with a tag
+// containing both "mysql" and "slow" (e.g., mysql-slow).
+func NewMysqlLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	matches := mysqlRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return nil
+	}
+
+	tag := matches[1]
+	content := matches[2]
+
+	switch tag {
+	case "mysqld", "mysqld_safe":
+		return newMysqlErrorLogEvent(header, tag, content)
+	}
+
+	lowerTag := strings.ToLower(tag)
+
+	if !strings.Contains(lowerTag, "mysql") || !strings.Contains(lowerTag, "slow") {
+		return nil
+	}
+
+	return newMysqlSlowQueryLogEvent(header, tag, content)
+}
+
+// newMysqlErrorLogEvent parses error log messages in the formats of MySQL
+// 5.5 ("161017 10:00:00 [ERROR] ..."), 5.6 and 5.7 ("2016-10-17 10:00:00 123
+// [ERROR] ...") and 8 ("2016-10-17T10:00:00.123456Z 123 [ERROR] [MY-010000]
+// [Server] ..."). Messages without a level (e.g., from mysqld_safe) are kept
+// as they are.
+func newMysqlErrorLogEvent(
+	header SyslogHeader,
+	tag string,
+	message string,
+) *MysqlLogEvent {
+	event := &MysqlLogEvent{
+		SyslogHeader: header,
+		Tag:          tag,
+		Content:      message,
+	}
+
+	matches := mysqlErrorLogRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return event
+	}
+
+	event.ThreadId, _ = strconv.Atoi(matches[1])
+	event.LogLevel = strings.ToUpper(matches[2])
+	event.Code = matches[3]
+	event.Subsystem = matches[4]
+	event.Content = matches[5]
+
+	return event
+}
+
+// newMysqlSlowQueryLogEvent parses one line of a slow query log entry:
+//
+//	# Time: 2016-10-17T10:00:00.123456Z
+//	# User@Host: app[app] @ localhost []  Id:    12
+//	# Query_time: 2.345678  Lock_time: 0.000123 Rows_sent: 1  Rows_examined: 123456
+//	use store;
+//	SET timestamp=1476698400;
+//	SELECT * FROM products WHERE id = 5;
+//
+// The Assembler puts the lines of an entry back together.
+func newMysqlSlowQueryLogEvent(
+	header SyslogHeader,
+	tag string,
+	message string,
+) *MysqlSlowQueryLogEvent {
+	event := &MysqlSlowQueryLogEvent{
+		SyslogHeader: header,
+		Tag:          tag,
+	}
+
+	if strings.HasPrefix(message, "# Time: ") {
+		event.part = "time"
+
+		return event
+	}
+
+	if matches := mysqlSlowLogUserRegexp.FindStringSubmatch(message); matches != nil {
+		event.part = "user-host"
+		event.User = matches[1]
+		event.Host = matches[2]
+
+		if event.Host == "" {
+			event.Host = matches[3]
+		}
+
+		event.ThreadId, _ = strconv.Atoi(matches[4])
+
+		return event
+	}
+
+	if strings.HasPrefix(message, "# Query_time: ") {
+		event.part = "query-time"
+
+		for _, match := range mysqlSlowLogStatRegexp.FindAllStringSubmatch(message, -1) {
+			switch match[1] {
+			case "Query_time":
+				event.QueryTime, _ = strconv.ParseFloat(match[2], 64)
+			case "Lock_time":
+				event.LockTime, _ = strconv.ParseFloat(match[2], 64)
+			case "Rows_sent":
+				event.RowsSent, _ = strconv.Atoi(match[2])
+			case "Rows_examined":
+				event.RowsExamined, _ = strconv.Atoi(match[2])
+			}
+		}
+
+		return event
+	}
+
+	if strings.HasPrefix(message, "#") {
+		event.part = "comment"
+
+		return event
+	}
+
+	if matches := mysqlSlowLogUseRegexp.FindStringSubmatch(message); matches != nil {
+		event.part = "use"
+		event.Database = matches[1]
+
+		return event
+	}
+
+	if strings.HasPrefix(message, "SET timestamp=") {
+		event.part = "set-timestamp"
+
+		return event
+	}
+
+	event.part = "sql"
+	event.Query = message
+	event.Fingerprint = MysqlFingerprint(message)
+
+	return event
+}
+
+// MysqlFingerprint normalises a query by replacing its literals with ?,
+// collapsing lists of values, stripping comments and whitespace, and
+// lowercasing it, in the style of pt-fingerprint.
+func MysqlFingerprint(query string) string {
+	for _, replacement := range mysqlFingerprintReplacements {
+		query = replacement.re.ReplaceAllString(query, replacement.replacement)
+	}
+
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(query), ";"))
+}
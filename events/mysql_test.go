@@ -0,0 +1,112 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMysqlLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message   string
+		summary   string
+		threadId  int
+		code      string
+		subsystem string
+		content   string
+	}{
+		{
+			"mysqld: 2026-10-17T10:00:00.123456Z 0 [ERROR] [MY-010119] [Server] Aborting",
+			"mysql-error", 0, "MY-010119", "Server", "Aborting",
+		},
+		{
+			"mysqld[1500]: 2026-10-17 10:00:00 140234 [Warning] Aborted connection 42 to db: 'shop' user: 'app' host: '10.0.0.5' (Got timeout reading communication packets)",
+			"mysql-warning", 140234, "", "",
+			"Aborted connection 42 to db: 'shop' user: 'app' host: '10.0.0.5' (Got timeout reading communication packets)",
+		},
+		{
+			"mysqld: 261017 10:00:00 [Note] /usr/sbin/mysqld: ready for connections.",
+			"mysql-note", 0, "", "", "/usr/sbin/mysqld: ready for connections.",
+		},
+		{
+			"mysqld_safe: Starting mysqld daemon with databases from /var/lib/mysql",
+			"mysql", 0, "", "", "Starting mysqld daemon with databases from /var/lib/mysql",
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*MysqlLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't a mysql event", test.message)
+			continue
+		}
+
+		if event.Summary() != test.summary ||
+			event.ThreadId != test.threadId ||
+			event.Code != test.code ||
+			event.Subsystem != test.subsystem ||
+			event.Content != test.content {
+			t.Errorf("%q parsed as %+v", test.message, event)
+		}
+	}
+}
+
+// TestMysqlSlowQuery checks that the lines of slow log entries are put back
+// together, and that a new entry starts with its "# Time:" line or, when
+// that's left out, with its "# User@Host:" line.
+func TestMysqlSlowQuery(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	released := []LogEventInterface{}
+
+	for _, line := range []string{
+		"mysql-slow: # Time: 2026-10-17T10:00:00.123456Z",
+		"mysql-slow: # User@Host: app[app] @ web1 [10.0.0.5]  Id:    42",
+		"mysql-slow: # Query_time: 2.500000  Lock_time: 0.000100 Rows_sent: 1  Rows_examined: 100000",
+		"mysql-slow: use shop;",
+		"mysql-slow: SET timestamp=1792231200;",
+		"mysql-slow: SELECT * FROM orders WHERE customer_id = 123 AND status IN ('a', 'b');",
+		"mysql-slow: # User@Host: app[app] @  [10.0.0.6]  Id:    43",
+		"mysql-slow: # Query_time: 1.000000  Lock_time: 0.000000 Rows_sent: 0  Rows_examined: 5000",
+		"mysql-slow: SET timestamp=1792231201;",
+		"mysql-slow: SELECT * FROM orders WHERE customer_id = 456 AND status IN ('c');",
+	} {
+		released = append(released, assembler.Add(parseTestLine(t, 0, line))...)
+	}
+
+	released = append(released, assembler.Flush()...)
+
+	if len(released) != 2 {
+		t.Fatalf("released %q, want two slow queries", summariesOf(released))
+	}
+
+	first := released[0].(*MysqlSlowQueryLogEvent)
+	second := released[1].(*MysqlSlowQueryLogEvent)
+
+	if first.User != "app" || first.Host != "web1" || first.ThreadId != 42 ||
+		first.QueryTime != 2.5 || first.RowsExamined != 100000 ||
+		first.Database != "shop" {
+		t.Errorf("first query = %+v", first)
+	}
+
+	if second.Host != "10.0.0.6" || second.ThreadId != 43 || second.QueryTime != 1 {
+		t.Errorf("second query = %+v", second)
+	}
+
+	want := "select * from orders where customer_id = ? and status in (?+)"
+
+	if first.Fingerprint != want || second.Fingerprint != want {
+		t.Errorf("fingerprints = %q, %q, want %q", first.Fingerprint, second.Fingerprint, want)
+	}
+}
+
+func TestMysqlFingerprint(t *testing.T) {
+	for query, want := range map[string]string{
+		"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y');":  "insert into t (a, b) values (?+)",
+		"SELECT /* web1 */ id FROM t WHERE name = \"bob\"": "select id from t where name = ?",
+		"select  id\n  from t where x = 0x1F -- trailing":  "select id from t where x = ?",
+		"UPDATE t SET price = -1.5e3 WHERE id = 7":         "update t set price = ? where id = ?",
+	} {
+		if fingerprint := MysqlFingerprint(query); fingerprint != want {
+			t.Errorf("MysqlFingerprint(%q) = %q, want %q", query, fingerprint, want)
+		}
+	}
+}
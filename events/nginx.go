@@ -5,19 +5,40 @@ import (
     "log"
     "regexp"
     "strconv"
+    "strings"
+    "sync"
     "time"
 
     ct       "github.com/daviddengcn/go-colortext"
     settings "github.com/lovek323/bclog/settings"
 )
 
+var nginxVariableRegexp = regexp.MustCompile("\\$(?:\\{([a-z0-9_]+)\\}|([a-z0-9_]+))")
+var nginxErrorRegexp = regexp.MustCompile(
+    "^nginx:\\s+(?:[0-9]{4}/[0-9]{2}/[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2} )?"+
+    "\\[(?P<level>[a-z]+)\\] "+
+    "(?:(?P<pid>[0-9]+)#(?P<tid>[0-9]+): )?"+
+    "(?:\\*(?P<connectionId>[0-9]+) )?"+
+    "(?P<content>.*)$",
+)
+var nginxContextRegexp = regexp.MustCompile(", (?:client|server): ")
+var nginxContextPairRegexp = regexp.MustCompile(
+    "(?:^|, )([a-z]+): (\"(?:[^\"\\\\]|\\\\.)*\"|[^,]*)",
+)
+
 type NginxAccessLogEvent struct {
-    SyslogTime time.Time
-    Source     string
-    Hostname   string
-    IpAddress  string
-    Time       time.Time
-    Request    NginxLogEventRequest
+    SyslogHeader
+    Hostname             string
+    IpAddress            string
+    Time                 time.Time
+    Request              NginxLogEventRequest
+    Referer              string
+    UserAgent            string
+    RequestTime          float64 // seconds, -1 if not logged
+    UpstreamResponseTime string
+    UpstreamAddress      string
+    Slow                 bool
+    Variables            map[string]string
 }
 
 type NginxLogEventRequest struct {
@@ -25,7 +46,7 @@ type NginxLogEventRequest struct {
     Uri             string
     ProtocolVersion string
     StatusCode      int
-    ContentLength   int
+    ContentLength   int64
 }
 
 func (e *NginxAccessLogEvent) PrintLine(index int) {
@@ -35,6 +56,9 @@ func (e *NginxAccessLogEvent) PrintLine(index int) {
     if e.Request.StatusCode >= 500 {
         background = ct.Red
         bold       = false
+    } else if e.Slow {
+        background = ct.Magenta
+        bold       = false
     } else {
         background = ct.None
         bold       = true
@@ -42,29 +66,45 @@ func (e *NginxAccessLogEvent) PrintLine(index int) {
 
     fmt.Printf("[%d]  ", index)
     fmt.Print(e.Time.Format("2006-01-02 15:04:05")+"  ")
+    fmt.Printf("%s  ", e.Source)
     ct.ChangeColor(ct.Yellow, bold, background, false)
     fmt.Print("nginx-access  ")
     ct.ChangeColor(ct.Cyan, bold, background, false)
     fmt.Printf("%s-%d  ", e.Request.Method, e.Request.StatusCode)
-    fmt.Printf("%s\n", e.Request.Uri)
+
+    if e.RequestTime >= 0 {
+        fmt.Printf("%s  (%.3fs)\n", e.Request.Uri, e.RequestTime)
+    } else {
+        fmt.Printf("%s\n", e.Request.Uri)
+    }
+
     ct.ResetColor()
 }
 
 func (e *NginxAccessLogEvent) PrintFull() {
     fmt.Printf("\n---------- NGINX ACCESS LOG EVENT ----------\n");
     fmt.Printf(
-        "SyslogTime:      %s\n",
+        "SyslogTime:           %s\n",
         e.SyslogTime.Format("2006-01-02 15:04:05"),
     )
-    fmt.Printf("Source:          %s\n", e.Source)
-    fmt.Printf("Hostname:        %s\n", e.Hostname)
-    fmt.Printf("IpAddress:       %s\n", e.IpAddress)
-    fmt.Printf("Time:            %s\n", e.Time.Format("2006-01-02 15:04:05"))
-    fmt.Printf("Method:          %s\n", e.Request.Method)
-    fmt.Printf("Uri:             %s\n", e.Request.Uri)
-    fmt.Printf("ProtocolVersion: %s\n", e.Request.ProtocolVersion)
-    fmt.Printf("StatusCode:      %d\n", e.Request.StatusCode)
-    fmt.Printf("ContentLength:   %d\n", e.Request.ContentLength)
+    fmt.Printf("Source:               %s\n", e.Source)
+    fmt.Printf("Hostname:             %s\n", e.Hostname)
+    fmt.Printf("IpAddress:            %s\n", e.IpAddress)
+    fmt.Printf("Time:                 %s\n", e.Time.Format("2006-01-02 15:04:05"))
+    fmt.Printf("Method:               %s\n", e.Request.Method)
+    fmt.Printf("Uri:                  %s\n", e.Request.Uri)
+    fmt.Printf("ProtocolVersion:      %s\n", e.Request.ProtocolVersion)
+    fmt.Printf("StatusCode:           %d\n", e.Request.StatusCode)
+    fmt.Printf("ContentLength:        %d\n", e.Request.ContentLength)
+    fmt.Printf("Referer:              %s\n", e.Referer)
+    fmt.Printf("UserAgent:            %s\n", e.UserAgent)
+
+    if e.RequestTime >= 0 {
+        fmt.Printf("RequestTime:          %.3fs\n", e.RequestTime)
+    }
+
+    fmt.Printf("UpstreamResponseTime: %s\n", e.UpstreamResponseTime)
+    fmt.Printf("UpstreamAddress:      %s\n", e.UpstreamAddress)
     fmt.Printf("--------------------------------------------\n\n");
 }
 
@@ -72,9 +112,14 @@ func (e *NginxAccessLogEvent) Summary() string {
     return "nginx-access-"+strconv.FormatInt(int64(e.Request.StatusCode), 10)
 }
 
+// Suppress never hides slow requests, even ones with a suppressed status code.
 func (e *NginxAccessLogEvent) Suppress(
     settings_ settings.SettingsInterface,
 ) bool {
+    if e.Slow {
+        return false
+    }
+
     for _, statusCode := range settings_.GetNginxSuppressStatusCodes() {
         if e.Request.StatusCode == statusCode {
             return true
@@ -84,148 +129,497 @@ func (e *NginxAccessLogEvent) Suppress(
     return false
 }
 
-func (e *NginxAccessLogEvent) GetSyslogTime() time.Time {
-    return e.SyslogTime
+// GetField returns the variables of the log format by their nginx names
+// (e.g., http_user_agent), along with the method, uri and status of the
+// request.
+func (e *NginxAccessLogEvent) GetField(name string) (string, bool) {
+    switch name {
+    case "method":
+        return e.Request.Method, true
+    case "uri":
+        return e.Request.Uri, true
+    case "status":
+        return strconv.Itoa(e.Request.StatusCode), true
+    }
+
+    value, ok := e.Variables[name]
+
+    return value, ok
+}
+
+// DefaultNginxLogFormat is the log_format used by the BigCommerce VM.
+const DefaultNginxLogFormat =
+    "$host $remote_addr - $remote_user [$time_local]  \"$request\" $status "+
+    "$body_bytes_sent \"$http_referer\" \"$http_user_agent\""
+
+// NginxLogFormat is an nginx log_format compiled into a regular expression
+// with a named group for each variable.
+type NginxLogFormat struct {
+    Format string
+    regexp *regexp.Regexp
+}
+
+// nginxVariablePatterns holds the patterns of variables whose values have a
+// known shape. Other variables match anything.
+var nginxVariablePatterns = map[string]string{
+    "time_local":      "[0-9]{2}/[A-Za-z]{3}/[0-9]{4}:[0-9]{2}:[0-9]{2}:[0-9]{2} [+-][0-9]{4}",
+    "time_iso8601":    "[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9:]{8}(?:Z|[+-][0-9]{2}:[0-9]{2})",
+    "remote_addr":     "[0-9A-Fa-f\\.:]+",
+    "status":          "[0-9]{3}",
+    "body_bytes_sent": "[0-9]+|-",
+    "bytes_sent":      "[0-9]+|-",
+    "request_time":    "[0-9]+(?:\\.[0-9]+)?|-",
+}
+
+func NewNginxLogFormat(format string) (*NginxLogFormat, error) {
+    pattern := "^nginx: "
+    last    := 0
+
+    for _, indexes := range nginxVariableRegexp.FindAllStringSubmatchIndex(format, -1) {
+        pattern += regexp.QuoteMeta(format[last:indexes[0]])
+        last     = indexes[1]
+
+        // The name is in the first group for ${name} and the second for $name.
+        name := ""
+
+        if indexes[2] != -1 {
+            name = format[indexes[2]:indexes[3]]
+        } else {
+            name = format[indexes[4]:indexes[5]]
+        }
+
+        variablePattern, ok := nginxVariablePatterns[name]
+
+        if !ok && indexes[0] > 0 && format[indexes[0]-1] == '"' {
+            // Quoted variables run up to the closing quote, since nginx
+            // escapes quotes in them.
+            variablePattern = "[^\"]*"
+        } else if !ok && indexes[1] == len(format) {
+            // Nothing follows the last variable to stop a lazy match.
+            variablePattern = ".*"
+        } else if !ok {
+            variablePattern = ".*?"
+        }
+
+        pattern += "(?P<"+name+">"+variablePattern+")"
+    }
+
+    // Anything logged after the end of the format is ignored, so that extra
+    // fields can be added to the end of log_format.
+    pattern += regexp.QuoteMeta(format[last:])+"(?: .*)?$"
+
+    re, err := regexp.Compile(pattern)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return &NginxLogFormat{Format: format, regexp: re}, nil
+}
+
+// Match returns the value of each variable in message, or nil if message
+// doesn't follow the format.
+func (f *NginxLogFormat) Match(message string) map[string]string {
+    matches := f.regexp.FindStringSubmatch(message)
+
+    if matches == nil {
+        return nil
+    }
+
+    variables := make(map[string]string)
+
+    for i, name := range f.regexp.SubexpNames() {
+        if name != "" {
+            variables[name] = matches[i]
+        }
+    }
+
+    return variables
 }
 
 type NginxErrorLogEvent struct {
-    SyslogTime time.Time
-    LogLevel   string
-    Content    string
-    Client     string
-    Server     string
-    Request    NginxLogEventRequest
-    Host       string
-    Referrer   string
+    SyslogHeader
+    LogLevel     string
+    Pid          int
+    Tid          int
+    ConnectionId int
+    Content      string
+    Client       string
+    Server       string
+    Request      NginxLogEventRequest
+    Upstream     string
+    Host         string
+    Referrer     string
+}
+
+// isSevere reports whether the level is error or worse.
+func (e *NginxErrorLogEvent) isSevere() bool {
+    switch e.LogLevel {
+    case "error", "crit", "alert", "emerg":
+        return true
+    }
+
+    return false
 }
 
 func (e *NginxErrorLogEvent) PrintLine(index int) {
     fmt.Printf("[%d]  ", index)
 
-    if e.LogLevel == "error" {
+    description := e.Content
+
+    if e.Request.Uri != "" {
+        description = e.Request.Uri+" "+e.Content
+    }
+
+    if e.isSevere() {
         fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05")+"  ")
+        fmt.Printf("%s  ", e.Source)
         ct.ChangeColor(ct.Yellow, false, ct.Red, false)
         fmt.Print("nginx-error  ")
         ct.ChangeColor(ct.Cyan, false, ct.Red, false)
         fmt.Printf("%s  ", e.LogLevel)
         ct.ChangeColor(ct.None, false, ct.Red, false)
-        fmt.Printf("%s %s\n", e.Request.Uri, e.Content)
+        fmt.Printf("%s\n", description)
         ct.ResetColor()
     } else {
         fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05")+"  ")
+        fmt.Printf("%s  ", e.Source)
         ct.ChangeColor(ct.Yellow, false, ct.None, false)
         fmt.Print("nginx-error  ")
         ct.ChangeColor(ct.Cyan, false, ct.None, false)
         fmt.Printf("%s  ", e.LogLevel)
         ct.ResetColor()
-        fmt.Printf("%s %s\n", e.Request.Uri, e.Content)
+        fmt.Printf("%s\n", description)
     }
 }
 
 func (e *NginxErrorLogEvent) PrintFull() {
+    fmt.Printf("\n---------- NGINX ERROR LOG EVENT ----------\n");
+    fmt.Printf(
+        "SyslogTime:      %s\n",
+        e.SyslogTime.Format("2006-01-02 15:04:05"),
+    )
+    fmt.Printf("Source:          %s\n", e.Source)
+    fmt.Printf("LogLevel:        %s\n", e.LogLevel)
+    fmt.Printf("Pid:             %d\n", e.Pid)
+    fmt.Printf("Tid:             %d\n", e.Tid)
+
+    if e.ConnectionId != 0 {
+        fmt.Printf("ConnectionId:    %d\n", e.ConnectionId)
+    }
+
+    fmt.Printf("Content:         %s\n", e.Content)
+
+    if e.Client != "" {
+        fmt.Printf("Client:          %s\n", e.Client)
+    }
+
+    if e.Server != "" {
+        fmt.Printf("Server:          %s\n", e.Server)
+    }
+
+    if e.Request.Method != "" {
+        fmt.Printf("Method:          %s\n", e.Request.Method)
+        fmt.Printf("Uri:             %s\n", e.Request.Uri)
+        fmt.Printf("ProtocolVersion: %s\n", e.Request.ProtocolVersion)
+    }
+
+    if e.Upstream != "" {
+        fmt.Printf("Upstream:        %s\n", e.Upstream)
+    }
+
+    if e.Host != "" {
+        fmt.Printf("Host:            %s\n", e.Host)
+    }
+
+    if e.Referrer != "" {
+        fmt.Printf("Referrer:        %s\n", e.Referrer)
+    }
+
+    fmt.Printf("-------------------------------------------\n\n");
 }
 
 func (e *NginxErrorLogEvent) Summary() string {
     return "nginx-error-"+e.LogLevel
 }
 
+// Suppress hides messages by their level and by the content regexes last
+// given to ConfigureNginxErrorSuppression.
 func (e *NginxErrorLogEvent) Suppress(
     settings_ settings.SettingsInterface,
 ) bool {
+    for _, level := range settings_.GetNginxErrorSuppressLogLevels() {
+        if e.LogLevel == level {
+            return true
+        }
+    }
+
+    nginxErrorSuppressMutex.RLock()
+    defer nginxErrorSuppressMutex.RUnlock()
+
+    for _, re := range nginxErrorSuppressRegexps {
+        if re.MatchString(e.Content) {
+            return true
+        }
+    }
+
     return false
 }
 
-func (e *NginxErrorLogEvent) GetSyslogTime() time.Time {
-    return e.SyslogTime
+// nginxErrorSuppressRegexps are the compiled NginxError.SuppressContentRegexes
+// of the config.
+var nginxErrorSuppressRegexps []*regexp.Regexp
+
+var nginxErrorSuppressMutex sync.RWMutex
+
+// ConfigureNginxErrorSuppression compiles the content regexes that error log
+// messages are suppressed by. It is called whenever the config is loaded, so
+// that a bad pattern is reported then rather than when a message reaches it.
+func ConfigureNginxErrorSuppression(patterns []string) error {
+    regexps := []*regexp.Regexp{}
+
+    for _, pattern := range patterns {
+        re, err := regexp.Compile(pattern)
+
+        if err != nil {
+            return fmt.Errorf("nginx error suppress regex %s: %s", pattern, err)
+        }
+
+        regexps = append(regexps, re)
+    }
+
+    nginxErrorSuppressMutex.Lock()
+    defer nginxErrorSuppressMutex.Unlock()
+
+    nginxErrorSuppressRegexps = regexps
+
+    return nil
+}
+
+func (e *NginxErrorLogEvent) GetField(name string) (string, bool) {
+    switch name {
+    case "level":
+        return e.LogLevel, true
+    case "client":
+        return e.Client, true
+    case "server":
+        return e.Server, true
+    case "uri":
+        return e.Request.Uri, true
+    case "upstream":
+        return e.Upstream, true
+    case "host":
+        return e.Host, true
+    }
+
+    return "", false
+}
+
+func init() {
+    format, err := NewNginxLogFormat(DefaultNginxLogFormat)
+
+    if err != nil {
+        log.Fatalf("Could not compile nginx log format: %s\n", err)
+    }
+
+    RegisterNginxParser(format, 0)
+}
+
+// RegisterNginxParser replaces the nginx parser with one for access logs in
+// format. Requests that take at least slowRequestTime seconds are marked as
+// slow; zero turns this off.
+func RegisterNginxParser(format *NginxLogFormat, slowRequestTime float64) {
+    RegisterParser(Parser{
+        Name:     "nginx",
+        Priority: 10,
+        Match:    func(header SyslogHeader, message string) LogEventInterface {
+            event := NewNginxLogEvent(header, message, format)
+
+            if event, ok := event.(*NginxAccessLogEvent); ok {
+                event.Slow = slowRequestTime > 0 &&
+                    event.RequestTime >= slowRequestTime
+            }
+
+            return event
+        },
+    })
 }
 
 func NewNginxLogEvent(
-    syslogTime time.Time,
-    source string,
+    header SyslogHeader,
     message string,
+    format *NginxLogFormat,
 ) LogEventInterface {
-    re := regexp.MustCompile(
-        "^nginx: (?P<hostname>.*?) (?P<ipAddress>[0-9\\.]*) (?:.*?) (?:.*?) "+
-        "\\[(?P<time>[0-9]{2}/(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)/[0-9]{4}:[0-9]{2}:[0-9]{2}:[0-9]{2} \\+[0-9]{4})\\]  "+
-        "\"(?P<method>.*?) (?P<uri>.*?) (?P<protocolVersion>.*?)\" "+
-        "(?P<statusCode>[0-9]{1,}) (?P<contentLength>[0-9]{1,}) (?:.*?) "+
-        "(?:.*?) (?:.*?)$",
-    )
-
-    matches := re.FindStringSubmatch(message)
+    variables := format.Match(message)
 
-    if matches == nil {
+    if variables == nil {
         // Search for other log messages.
-        re = regexp.MustCompile(
-            "^nginx:  \\[(?P<level>.*?)\\] (?P<content>.*?), "+
-            "client: (?P<clientIp>.*), server: (?P<server>.*), "+
-            "request: \"(?P<method>.*?) (?P<uri>.*?) "+
-            "(?P<protocolVersion>.*?)\", host: (?P<host>.*?)"+
-            "(?:, referrer: (?P<referrer>.*?)|)$",
-        )
+        return NewNginxErrorLogEvent(header, message)
+    }
+
+    // Lines whose values don't parse aren't access log lines after all, so
+    // they're left to the other parsers. Values nginx didn't log are "-".
+    time_ := header.SyslogTime
 
-        matches = re.FindStringSubmatch(message)
+    if value, ok := variables["time_local"]; ok {
+        parsed, err := time.Parse("02/Jan/2006:15:04:05 -0700", value)
 
-        if matches == nil {
+        if err != nil {
             return nil
         }
 
-        request := NginxLogEventRequest{
-            Method:          matches[5],
-            Uri:             matches[6],
-            ProtocolVersion: matches[7],
-            StatusCode:      0,
-            ContentLength:   0,
+        time_ = parsed.In(header.SyslogTime.Location())
+    } else if value, ok := variables["time_iso8601"]; ok {
+        parsed, err := time.Parse(time.RFC3339, value)
+
+        if err != nil {
+            return nil
         }
 
-        return &NginxErrorLogEvent{
-            SyslogTime: syslogTime,
-            LogLevel:   matches[1],
-            Content:    matches[2],
-            Client:     matches[3],
-            Server:     matches[4],
-            Request:    request,
-            Host:       matches[8],
-            Referrer:   matches[9],
+        time_ = parsed.In(header.SyslogTime.Location())
+    }
+
+    request := NginxLogEventRequest{}
+
+    // $request is "-" or truncated for requests nginx couldn't read, so
+    // method, uri and protocol are each optional.
+    parts := strings.SplitN(variables["request"], " ", 3)
+
+    request.Method = parts[0]
+
+    if len(parts) > 1 {
+        request.Uri = parts[1]
+    }
+
+    if len(parts) > 2 {
+        request.ProtocolVersion = parts[2]
+    }
+
+    if value, ok := variables["status"]; ok {
+        statusCode, err := strconv.Atoi(value)
+
+        if err != nil {
+            return nil
         }
+
+        request.StatusCode = statusCode
     }
 
-    time_, err := time.Parse("02/Jan/2006:15:04:05 -0700", matches[3])
+    contentLength := variables["body_bytes_sent"]
 
-    if err != nil {
-        log.Fatalf("Could not parse time: %s (%s)\n", matches[3])
+    if contentLength == "" {
+        contentLength = variables["bytes_sent"]
     }
 
-    statusCode, err := strconv.ParseInt(matches[7], 10, 32)
+    if contentLength != "" && contentLength != "-" {
+        value, err := strconv.ParseInt(contentLength, 10, 64)
 
-    if err != nil {
-        log.Fatalf("Could not parse status code: %s (%s)\n", matches[7], err)
+        if err != nil {
+            return nil
+        }
+
+        request.ContentLength = value
     }
 
-    contentLength, err := strconv.ParseInt(matches[8], 10, 32)
+    requestTime := -1.0
 
-    if err != nil {
-        log.Fatalf(
-            "Could not parse content length: %s (%s)\n",
-            matches[8],
-            err,
-        )
+    if value, ok := variables["request_time"]; ok && value != "-" {
+        parsed, err := strconv.ParseFloat(value, 64)
+
+        if err != nil {
+            return nil
+        }
+
+        requestTime = parsed
     }
 
-    request := NginxLogEventRequest{
-        Method:          matches[4],
-        Uri:             matches[5],
-        ProtocolVersion: matches[6],
-        StatusCode:      int(statusCode),
-        ContentLength:   int(contentLength),
+    upstreamResponseTime := variables["upstream_response_time"]
+
+    if upstreamResponseTime == "-" {
+        upstreamResponseTime = ""
     }
 
     return &NginxAccessLogEvent{
-        SyslogTime: syslogTime,
-        Source:     source,
-        Hostname:   matches[1],
-        IpAddress:  matches[2],
-        Time:       time_,
-        Request:    request,
+        SyslogHeader:         header,
+        Hostname:             variables["host"],
+        IpAddress:            variables["remote_addr"],
+        Time:                 time_,
+        Request:              request,
+        Referer:              variables["http_referer"],
+        UserAgent:            variables["http_user_agent"],
+        RequestTime:          requestTime,
+        UpstreamResponseTime: upstreamResponseTime,
+        UpstreamAddress:      variables["upstream_addr"],
+        Variables:            variables,
+    }
+}
+
+// NewNginxErrorLogEvent parses error log messages, which look like
+//
+//     [level] pid#tid: *connection message, client: ..., server: ..., ...
+//
+// The date, pid, connection and the trailing context are each optional, since
+// nginx leaves out whatever it doesn't know (e.g., messages from the master
+// process have no connection or request).
+func NewNginxErrorLogEvent(
+    header SyslogHeader,
+    message string,
+) LogEventInterface {
+    matches := nginxErrorRegexp.FindStringSubmatch(message)
+
+    if matches == nil {
+        return nil
     }
+
+    event := &NginxErrorLogEvent{
+        SyslogHeader: header,
+        LogLevel:     matches[1],
+        Content:      matches[5],
+    }
+
+    event.Pid, _          = strconv.Atoi(matches[2])
+    event.Tid, _          = strconv.Atoi(matches[3])
+    event.ConnectionId, _ = strconv.Atoi(matches[4])
+
+    // The context always starts with the client or, for requests that
+    // haven't got one, the server.
+    contextIndex := nginxContextRegexp.FindStringIndex(event.Content)
+
+    if contextIndex == nil {
+        return event
+    }
+
+    context      := event.Content[contextIndex[0]+2:]
+    event.Content = event.Content[:contextIndex[0]]
+
+    for _, pair := range nginxContextPairRegexp.FindAllStringSubmatch(context, -1) {
+        value := strings.Trim(pair[2], "\"")
+
+        switch pair[1] {
+        case "client":
+            event.Client = value
+        case "server":
+            event.Server = value
+        case "request":
+            parts := strings.SplitN(value, " ", 3)
+
+            event.Request.Method = parts[0]
+
+            if len(parts) > 1 {
+                event.Request.Uri = parts[1]
+            }
+
+            if len(parts) > 2 {
+                event.Request.ProtocolVersion = parts[2]
+            }
+        case "upstream":
+            event.Upstream = value
+        case "host":
+            event.Host = value
+        case "referrer":
+            event.Referrer = value
+        }
+    }
+
+    return event
 }
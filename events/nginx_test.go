@@ -0,0 +1,196 @@
+package events
+
+import (
+	"testing"
+
+	settings "github.com/lovek323/bclog/settings"
+)
+
+const testNginxLogFormat = "$remote_addr - $remote_user [$time_local] " +
+	"\"$request\" $status $body_bytes_sent \"$http_referer\" " +
+	"\"$http_user_agent\" $request_time $upstream_response_time $upstream_addr"
+
+func parseTestNginxLine(t *testing.T, format string, message string) LogEventInterface {
+	nginxFormat, err := NewNginxLogFormat(format)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := SyslogHeader{SyslogTime: testTime, Source: "web1", Facility: -1, Severity: -1}
+
+	return NewNginxLogEvent(header, message, nginxFormat)
+}
+
+func TestNginxAccessLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		format               string
+		message              string
+		uri                  string
+		statusCode           int
+		contentLength        int64
+		requestTime          float64
+		upstreamResponseTime string
+		upstreamAddress      string
+	}{
+		{
+			DefaultNginxLogFormat,
+			"nginx: store.example.com 10.0.0.5 - - [17/Oct/2026:10:00:00 +0000]  \"GET /cart.php HTTP/1.1\" 200 5120 \"https://store.example.com/\" \"Mozilla/5.0 (X11; Linux x86_64)\"",
+			"/cart.php", 200, 5120, -1, "", "",
+		},
+		{
+			testNginxLogFormat,
+			"nginx: 10.0.0.5 - - [17/Oct/2026:10:00:00 +0000] \"POST /api/orders HTTP/1.1\" 502 157 \"-\" \"curl/8.5.0\" 1.502 1.500 10.0.1.20:9000",
+			"/api/orders", 502, 157, 1.502, "1.500", "10.0.1.20:9000",
+		},
+		{
+			// Requests nginx answers itself log "-" for the upstream.
+			testNginxLogFormat,
+			"nginx: 10.0.0.5 - - [17/Oct/2026:10:00:00 +0000] \"GET /favicon.ico HTTP/1.1\" 404 0 \"-\" \"curl/8.5.0\" 0.000 - -",
+			"/favicon.ico", 404, 0, 0, "", "-",
+		},
+		{
+			testNginxLogFormat,
+			"nginx: 10.0.0.5 - - [17/Oct/2026:10:00:00 +0000] \"GET /export.zip HTTP/1.1\" 200 3221225472 \"-\" \"curl/8.5.0\" - - -",
+			"/export.zip", 200, 3221225472, -1, "", "-",
+		},
+	} {
+		event, ok := parseTestNginxLine(t, test.format, test.message).(*NginxAccessLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't an access log event", test.message)
+			continue
+		}
+
+		if event.Request.Uri != test.uri ||
+			event.Request.StatusCode != test.statusCode ||
+			event.Request.ContentLength != test.contentLength ||
+			event.RequestTime != test.requestTime ||
+			event.UpstreamResponseTime != test.upstreamResponseTime ||
+			event.UpstreamAddress != test.upstreamAddress {
+			t.Errorf("%q parsed as %+v", test.message, event)
+		}
+
+		if !event.Time.Equal(testTime) {
+			t.Errorf("%q time = %s", test.message, event.Time)
+		}
+	}
+}
+
+// TestNginxAccessLogEventInvalid checks that a line with a value that doesn't
+// parse is left to the other parsers.
+func TestNginxAccessLogEventInvalid(t *testing.T) {
+	message := "nginx: 10.0.0.5 - - [32/Oct/2026:10:00:00 +0000] \"GET / HTTP/1.1\" 200 0 \"-\" \"curl/8.5.0\" 0.001 - -"
+
+	if event := parseTestNginxLine(t, testNginxLogFormat, message); event != nil {
+		t.Errorf("%q parsed as %+v, want nil", message, event)
+	}
+}
+
+func TestNginxAccessLogEventSlow(t *testing.T) {
+	format, err := NewNginxLogFormat(testNginxLogFormat)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterNginxParser(format, 1)
+
+	defaultFormat, _ := NewNginxLogFormat(DefaultNginxLogFormat)
+	defer RegisterNginxParser(defaultFormat, 0)
+
+	event := parseTestLine(
+		t,
+		0,
+		"nginx: 10.0.0.5 - - [17/Oct/2026:10:00:00 +0000] \"GET /search HTTP/1.1\" 200 1024 \"-\" \"curl/8.5.0\" 2.250 2.248 10.0.1.20:9000",
+	).(*NginxAccessLogEvent)
+
+	if !event.Slow {
+		t.Errorf("a %.3fs request isn't slow", event.RequestTime)
+	}
+}
+
+func TestNginxErrorLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message      string
+		level        string
+		pid          int
+		connectionId int
+		content      string
+		client       string
+		uri          string
+		upstream     string
+	}{
+		{
+			"nginx: 2026/10/17 10:00:00 [error] 1234#1234: *56 upstream timed out (110: Connection timed out) while reading response header from upstream, client: 10.0.0.5, server: store.example.com, request: \"GET /checkout HTTP/1.1\", upstream: \"fastcgi://unix:/run/php/php-fpm.sock:\", host: \"store.example.com\"",
+			"error", 1234, 56,
+			"upstream timed out (110: Connection timed out) while reading response header from upstream",
+			"10.0.0.5", "/checkout", "fastcgi://unix:/run/php/php-fpm.sock:",
+		},
+		{
+			"nginx: 2026/10/17 10:00:00 [warn] 1234#1234: *57 an upstream response is buffered to a temporary file /var/cache/nginx/fastcgi_temp/1/00/0000000001 while reading upstream, client: 10.0.0.6, server: store.example.com, request: \"GET /export HTTP/1.1\"",
+			"warn", 1234, 57,
+			"an upstream response is buffered to a temporary file /var/cache/nginx/fastcgi_temp/1/00/0000000001 while reading upstream",
+			"10.0.0.6", "/export", "",
+		},
+		{
+			"nginx: [emerg] 1#1: bind() to 0.0.0.0:80 failed (98: Address already in use)",
+			"emerg", 1, 0, "bind() to 0.0.0.0:80 failed (98: Address already in use)",
+			"", "", "",
+		},
+		{
+			"nginx: 2026/10/17 10:00:00 [notice] 1#1: signal process started",
+			"notice", 1, 0, "signal process started", "", "", "",
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*NginxErrorLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't an error log event", test.message)
+			continue
+		}
+
+		if event.LogLevel != test.level ||
+			event.Pid != test.pid ||
+			event.ConnectionId != test.connectionId ||
+			event.Content != test.content ||
+			event.Client != test.client ||
+			event.Request.Uri != test.uri ||
+			event.Upstream != test.upstream {
+			t.Errorf("%q parsed as %+v", test.message, event)
+		}
+
+		if summary := event.Summary(); summary != "nginx-error-"+test.level {
+			t.Errorf("%q summary = %q", test.message, summary)
+		}
+	}
+}
+
+func TestNginxErrorLogEventSuppress(t *testing.T) {
+	settings_ := &settings.Settings{}
+	settings_.NginxError.SuppressLogLevels = []string{"notice"}
+
+	err := ConfigureNginxErrorSuppression([]string{"^an upstream response is buffered"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ConfigureNginxErrorSuppression(nil)
+
+	for message, want := range map[string]bool{
+		"nginx: 2026/10/17 10:00:00 [notice] 1#1: signal process started":                                                                     true,
+		"nginx: 2026/10/17 10:00:00 [warn] 1234#1234: *57 an upstream response is buffered to a temporary file /tmp/1 while reading upstream": true,
+		"nginx: 2026/10/17 10:00:00 [warn] 1234#1234: *58 client intended to send too large body: 10485760 bytes, client: 10.0.0.5":           false,
+	} {
+		if suppressed := parseTestLine(t, 0, message).Suppress(settings_); suppressed != want {
+			t.Errorf("%q suppressed = %t, want %t", message, suppressed, want)
+		}
+	}
+}
+
+func TestConfigureNginxErrorSuppressionInvalid(t *testing.T) {
+	if err := ConfigureNginxErrorSuppression([]string{"(unclosed"}); err == nil {
+		t.Error("ConfigureNginxErrorSuppression() accepted an invalid regex")
+	}
+}
@@ -0,0 +1,139 @@
+package events
+
+import (
+	"sort"
+	"sync"
+
+	settings "github.com/lovek323/bclog/settings"
+)
+
+// Parser turns syslog messages of one family into events. Match returns nil
+// for messages that don't belong to the family, in which case the parser with
+// the next highest priority (i.e., the next lowest Priority) gets a go.
+type Parser struct {
+	Name     string
+	Priority int
+	Match    func(header SyslogHeader, message string) LogEventInterface
+}
+
+var parsers = make(map[string]Parser)
+
+// enabledParsers is the registry in the order the parsers are tried, less
+// the disabled ones. It is rebuilt whenever the registry or the config
+// changes, rather than for every message.
+var enabledParsers []Parser
+
+var disabledParsers = make(map[string]bool)
+var parserPriorities = make(map[string]int)
+
+// parsersMutex guards the registry, since reloading the config replaces
+// custom parsers while the log is being read.
+var parsersMutex sync.RWMutex
+
+// RegisterParser adds a parser to the registry, replacing any parser that
+// was already registered with the same name. Event types register their
+// parsers from init().
+func RegisterParser(parser Parser) {
+	parsersMutex.Lock()
+	defer parsersMutex.Unlock()
+
+	parsers[parser.Name] = parser
+	sortParsers()
+}
+
+// UnregisterParser removes a parser from the registry.
+func UnregisterParser(name string) {
+	parsersMutex.Lock()
+	defer parsersMutex.Unlock()
+
+	delete(parsers, name)
+	sortParsers()
+}
+
+// replaceParsers removes the parsers named old from the registry and adds
+// added in their place, all at once, so that messages are never parsed with
+// only some of them in the registry.
+func replaceParsers(old []string, added []Parser) {
+	parsersMutex.Lock()
+	defer parsersMutex.Unlock()
+
+	for _, name := range old {
+		delete(parsers, name)
+	}
+
+	for _, parser := range added {
+		parsers[parser.Name] = parser
+	}
+
+	sortParsers()
+}
+
+// ConfigureParsers applies the disabled parsers and priorities in settings_,
+// which take precedence over the priorities the parsers were registered with.
+// It is called whenever the config is loaded.
+func ConfigureParsers(settings_ settings.SettingsInterface) {
+	parsersMutex.Lock()
+	defer parsersMutex.Unlock()
+
+	disabledParsers = make(map[string]bool)
+
+	for _, name := range settings_.GetDisabledParsers() {
+		disabledParsers[name] = true
+	}
+
+	parserPriorities = make(map[string]int)
+
+	for name, priority := range settings_.GetParserPriorities() {
+		parserPriorities[name] = priority
+	}
+
+	sortParsers()
+}
+
+// sortParsers rebuilds enabledParsers. parsersMutex must be held.
+func sortParsers() {
+	enabled := []Parser{}
+
+	for name, parser := range parsers {
+		if disabledParsers[name] {
+			continue
+		}
+
+		if priority, ok := parserPriorities[name]; ok {
+			parser.Priority = priority
+		}
+
+		enabled = append(enabled, parser)
+	}
+
+	sort.Slice(enabled, func(i, j int) bool {
+		if enabled[i].Priority == enabled[j].Priority {
+			return enabled[i].Name < enabled[j].Name
+		}
+
+		return enabled[i].Priority < enabled[j].Priority
+	})
+
+	enabledParsers = enabled
+}
+
+// Parsers returns the enabled parsers, in the order they are tried. The slice
+// must not be modified.
+func Parsers() []Parser {
+	parsersMutex.RLock()
+	defer parsersMutex.RUnlock()
+
+	return enabledParsers
+}
+
+// ParseEvent returns the event produced by the first enabled parser that
+// matches the message, or nil if none of them do.
+func ParseEvent(header SyslogHeader, message string) LogEventInterface {
+	for _, parser := range Parsers() {
+		if event := parser.Match(header, message); event != nil {
+			return event
+		}
+	}
+
+	return nil
+}
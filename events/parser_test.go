@@ -0,0 +1,85 @@
+package events
+
+import (
+	"testing"
+
+	settings "github.com/lovek323/bclog/settings"
+)
+
+// configureTestParsers applies settings_ to the registry and restores the
+// defaults when the test is done.
+func configureTestParsers(t *testing.T, settings_ *settings.Settings) {
+	ConfigureParsers(settings_)
+
+	t.Cleanup(func() { ConfigureParsers(&settings.Settings{}) })
+}
+
+func TestParsersOrder(t *testing.T) {
+	configureTestParsers(t, &settings.Settings{})
+
+	parsers := Parsers()
+
+	for i := 1; i < len(parsers); i++ {
+		if parsers[i-1].Priority > parsers[i].Priority {
+			t.Fatalf(
+				"%s (%d) is tried before %s (%d)",
+				parsers[i-1].Name,
+				parsers[i-1].Priority,
+				parsers[i].Name,
+				parsers[i].Priority,
+			)
+		}
+	}
+}
+
+func TestConfigureParsers(t *testing.T) {
+	settings_ := &settings.Settings{}
+	settings_.Parsers.Disabled = []string{"kernel"}
+	settings_.Parsers.Priorities = map[string]int{"generic": 1}
+
+	configureTestParsers(t, settings_)
+
+	parsers := Parsers()
+
+	if parsers[0].Name != "generic" || parsers[0].Priority != 1 {
+		t.Errorf("first parser = %s (%d), want generic (1)", parsers[0].Name, parsers[0].Priority)
+	}
+
+	for _, parser := range parsers {
+		if parser.Name == "kernel" {
+			t.Errorf("kernel parser is enabled")
+		}
+	}
+
+	event := ParseEvent(SyslogHeader{}, "kernel: Out of memory: Killed process 1234 (php-fpm)")
+
+	if _, ok := event.(*GenericLogEvent); !ok {
+		t.Errorf("ParseEvent() = %T, want *GenericLogEvent", event)
+	}
+}
+
+// TestRegisterParser checks that the order is rebuilt when a parser is
+// registered after the config has been loaded.
+func TestRegisterParser(t *testing.T) {
+	configureTestParsers(t, &settings.Settings{})
+
+	RegisterParser(Parser{
+		Name:     "test",
+		Priority: 0,
+		Match: func(header SyslogHeader, message string) LogEventInterface {
+			return NewRawLogEvent(header, message)
+		},
+	})
+
+	defer UnregisterParser("test")
+
+	if _, ok := ParseEvent(SyslogHeader{}, "kernel: hello").(*RawLogEvent); !ok {
+		t.Errorf("registered parser wasn't tried first")
+	}
+
+	UnregisterParser("test")
+
+	if _, ok := ParseEvent(SyslogHeader{}, "kernel: hello").(*KernelLogEvent); !ok {
+		t.Errorf("unregistered parser is still tried")
+	}
+}
@@ -0,0 +1,352 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+var phpFpmRegexp = regexp.MustCompile(
+	"^(?P<tag>php-fpm[^\\[:]*)(?:\\[(?P<pid>[0-9]{1,})\\])?: " +
+		"(?:\\[(?P<level>[A-Z]+)\\]|(?P<fileLevel>[A-Z]+):) " +
+		"(?:\\[pool (?P<pool>[^\\]]+)\\] )?(?P<content>.*)$",
+)
+var phpFpmExitedRegexp = regexp.MustCompile(
+	"^child (?P<pid>[0-9]{1,}) exited " +
+		"(?:with code (?P<code>[0-9]{1,})|on signal (?P<signal>[0-9]{1,})[^)]*\\)) " +
+		"after (?P<duration>[0-9.]+) seconds from start$",
+)
+var phpFpmSlowRequestRegexp = regexp.MustCompile(
+	"^child (?P<pid>[0-9]{1,}), script '(?P<script>[^']*)' " +
+		"\\(request: \"(?P<request>[^\"]*)\"\\) executing too slow " +
+		"\\((?P<duration>[0-9.]+) sec\\), logging$",
+)
+var phpFpmSlowlogRegexp = regexp.MustCompile(
+	"^(?P<tag>[^ :]+): \\[[0-9]{2}-[A-Za-z]{3}-[0-9]{4} [0-9:]{8}\\]\\s+" +
+		"\\[pool (?P<pool>[^\\]]+)\\] pid (?P<pid>[0-9]{1,})$",
+)
+var phpFpmSlowlogScriptRegexp = regexp.MustCompile("^(?P<tag>[^ :]+): script_filename = (?P<script>.*)$")
+var phpFpmSlowlogFrameRegexp = regexp.MustCompile(
+	"^(?P<tag>[^ :]+): \\[0x[0-9a-f]+\\] (?P<function>.*?)\\(\\) " +
+		"(?P<file>[^ ]+):(?P<line>[0-9]{1,})$",
+)
+
+// PhpFpmLogEvent is a message from the PHP-FPM master process or an entry of
+// its slow log. Kind says what the message is about:
+//
+//	max-children  the pool reached pm.max_children
+//	busy          the pool is spawning children to keep up
+//	exited        a child exited (Duration is how long it ran)
+//	slow-request  a request ran longer than request_slowlog_timeout
+//	slowlog       a slow log entry with the script's stack
+//
+// Other messages have an empty Kind. The script and frame lines of a slow log
+// entry are parsed with the Kinds slowlog-script and slowlog-frame, and are
+// added to the entry by the Assembler.
+type PhpFpmLogEvent struct {
+	SyslogHeader
+	Tag        string
+	Kind       string
+	LogLevel   string
+	Pool       string
+	Pid        int
+	Duration   float64 // seconds, -1 if not logged
+	ExitCode   int
+	Signal     int
+	Script     string
+	Request    string
+	Content    string
+	StackTrace []PhpFpmStackFrame
+}
+
+// PhpFpmStackFrame is one frame of a slow log entry.
+type PhpFpmStackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// isCapacityWarning reports whether the event means the pool is short of
+// workers or they are dying or hanging.
+func (e *PhpFpmLogEvent) isCapacityWarning() bool {
+	switch e.Kind {
+	case "max-children", "slow-request", "slowlog":
+		return true
+	case "exited":
+		return e.ExitCode != 0 || e.Signal != 0
+	}
+
+	return false
+}
+
+func (e *PhpFpmLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	if e.isCapacityWarning() {
+		background = ct.Red
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("php-fpm  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s-%s-%d  ", e.LogLevel, e.Pool, e.Pid)
+	ct.ChangeColor(ct.None, false, background, false)
+
+	if e.Kind == "slowlog" && len(e.StackTrace) > 0 {
+		frame := e.StackTrace[0]
+		fmt.Printf("%s in %s() %s:%d\n", e.Script, frame.Function, frame.File, frame.Line)
+	} else {
+		fmt.Printf("%s\n", e.Content)
+	}
+
+	ct.ResetColor()
+}
+
+func (e *PhpFpmLogEvent) PrintFull() {
+	fmt.Printf("\n---------- PHP-FPM LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Kind:\t%s\n", e.Kind)
+	fmt.Fprintf(writer, "LogLevel:\t%s\n", e.LogLevel)
+	fmt.Fprintf(writer, "Pool:\t%s\n", e.Pool)
+	fmt.Fprintf(writer, "Pid:\t%d\n", e.Pid)
+
+	if e.Duration >= 0 {
+		fmt.Fprintf(writer, "Duration:\t%.3fs\n", e.Duration)
+	}
+
+	if e.Kind == "exited" {
+		fmt.Fprintf(writer, "ExitCode:\t%d\n", e.ExitCode)
+		fmt.Fprintf(writer, "Signal:\t%d\n", e.Signal)
+	}
+
+	fmt.Fprintf(writer, "Script:\t%s\n", e.Script)
+	fmt.Fprintf(writer, "Request:\t%s\n", e.Request)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	writer.Flush()
+
+	if len(e.StackTrace) > 0 {
+		ct.ChangeColor(ct.White, true, ct.None, false)
+		fmt.Print("\nStack trace\n")
+		ct.ResetColor()
+
+		for i, frame := range e.StackTrace {
+			fmt.Fprintf(
+				writer,
+				"%d.\t%s\t%s\t%d\n",
+				i,
+				frame.Function,
+				frame.File,
+				frame.Line,
+			)
+		}
+
+		writer.Flush()
+	}
+
+	fmt.Printf("\n---------- PHP-FPM LOG EVENT ----------\n")
+}
+
+func (e *PhpFpmLogEvent) Summary() string {
+	if e.Kind == "" {
+		return "php-fpm-" + strings.ToLower(e.LogLevel)
+	}
+
+	return "php-fpm-" + e.Kind
+}
+
+func (e *PhpFpmLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	for _, level := range settings_.GetPhpFpmSuppressLogLevels() {
+		if e.LogLevel == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *PhpFpmLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "pool":
+		return e.Pool, true
+	case "pid":
+		return strconv.Itoa(e.Pid), true
+	case "script":
+		return e.Script, true
+	}
+
+	return "", false
+}
+
+// addSlowlogLine adds the script or a frame of a slow log entry to it.
+func (e *PhpFpmLogEvent) addSlowlogLine(line *PhpFpmLogEvent) {
+	if line.Kind == "slowlog-script" {
+		e.Script = line.Script
+	} else {
+		e.StackTrace = append(e.StackTrace, line.StackTrace...)
+	}
+}
+
+// assemble holds slow log entries back until their script and frame lines
+// have been added.
+func (e *PhpFpmLogEvent) assemble(a *Assembler) []LogEventInterface {
+	key := "php-fpm " + e.Source + " " + e.Tag
+
+	switch e.Kind {
+	case "slowlog":
+		complete := a.release(key)
+		a.hold(key, e)
+
+		return complete
+	case "slowlog-script", "slowlog-frame":
+		pending, ok := a.pending[key]
+
+		if !ok || e.SyslogTime.Sub(pending.last) > a.Window {
+			return append(a.release(key), e)
+		}
+
+		pending.event.(*PhpFpmLogEvent).addSlowlogLine(e)
+		pending.last = e.SyslogTime
+
+		return nil
+	}
+
+	return []LogEventInterface{e}
+}
+
+func init() {
+	RegisterParser(Parser{
+		Name:     "php-fpm",
+		Priority: 35,
+		Match:    NewPhpFpmLogEvent,
+	})
+}
+
+func NewPhpFpmLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	if event := newPhpFpmSlowlogEvent(header, message); event != nil {
+		return event
+	}
+
+	matches := phpFpmRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return nil
+	}
+
+	event := &PhpFpmLogEvent{
+		SyslogHeader: header,
+		Tag:          matches[1],
+		LogLevel:     matches[3] + matches[4],
+		Pool:         matches[5],
+		Duration:     -1,
+		Content:      matches[6],
+	}
+
+	event.Pid, _ = strconv.Atoi(matches[2])
+
+	content := matches[6]
+
+	if strings.HasPrefix(content, "server reached ") &&
+		strings.Contains(content, "max_children") {
+		event.Kind = "max-children"
+	} else if strings.HasPrefix(content, "seems busy") {
+		event.Kind = "busy"
+	}
+
+	if matches = phpFpmExitedRegexp.FindStringSubmatch(content); matches != nil {
+		event.Kind = "exited"
+		event.Pid, _ = strconv.Atoi(matches[1])
+		event.ExitCode, _ = strconv.Atoi(matches[2])
+		event.Signal, _ = strconv.Atoi(matches[3])
+		event.Duration, _ = strconv.ParseFloat(matches[4], 64)
+	}
+
+	if matches = phpFpmSlowRequestRegexp.FindStringSubmatch(content); matches != nil {
+		event.Kind = "slow-request"
+		event.Pid, _ = strconv.Atoi(matches[1])
+		event.Script = matches[2]
+		event.Request = matches[3]
+		event.Duration, _ = strconv.ParseFloat(matches[4], 64)
+	}
+
+	return event
+}
+
+// newPhpFpmSlowlogEvent parses the lines of a slow log entry, which looks
+// like
+//
+//	[17-Oct-2026 10:00:00]  [pool www] pid 5678
+//	script_filename = /var/www/index.php
+//	[0x00007f3b2c8e4f10] curl_exec() /var/www/lib/Http.php:52
+//
+// when the slow log is forwarded to syslog.
+func newPhpFpmSlowlogEvent(
+	header SyslogHeader,
+	message string,
+) *PhpFpmLogEvent {
+	if matches := phpFpmSlowlogRegexp.FindStringSubmatch(message); matches != nil {
+		pid, _ := strconv.Atoi(matches[3])
+
+		return &PhpFpmLogEvent{
+			SyslogHeader: header,
+			Tag:          matches[1],
+			Kind:         "slowlog",
+			LogLevel:     "WARNING",
+			Pool:         matches[2],
+			Pid:          pid,
+			Duration:     -1,
+			Content:      "slow log entry",
+		}
+	}
+
+	if matches := phpFpmSlowlogScriptRegexp.FindStringSubmatch(message); matches != nil {
+		return &PhpFpmLogEvent{
+			SyslogHeader: header,
+			Tag:          matches[1],
+			Kind:         "slowlog-script",
+			LogLevel:     "WARNING",
+			Duration:     -1,
+			Script:       matches[2],
+			Content:      "script_filename = " + matches[2],
+		}
+	}
+
+	if matches := phpFpmSlowlogFrameRegexp.FindStringSubmatch(message); matches != nil {
+		line, _ := strconv.Atoi(matches[4])
+
+		return &PhpFpmLogEvent{
+			SyslogHeader: header,
+			Tag:          matches[1],
+			Kind:         "slowlog-frame",
+			LogLevel:     "WARNING",
+			Duration:     -1,
+			Content:      matches[2] + "() " + matches[3] + ":" + matches[4],
+			StackTrace: []PhpFpmStackFrame{
+				{Function: matches[2], File: matches[3], Line: line},
+			},
+		}
+	}
+
+	return nil
+}
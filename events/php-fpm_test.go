@@ -0,0 +1,98 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPhpFpmLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message  string
+		summary  string
+		pool     string
+		pid      int
+		duration float64
+	}{
+		{
+			"php-fpm[900]: [WARNING] [pool www] server reached pm.max_children setting (10), consider raising it",
+			"php-fpm-max-children", "www", 900, -1,
+		},
+		{
+			"php-fpm[900]: [WARNING] [pool www] seems busy (you may need to increase pm.start_servers, or pm.min/max_spare_servers), spawning 8 children, there are 0 idle, and 12 total children",
+			"php-fpm-busy", "www", 900, -1,
+		},
+		{
+			"php-fpm[900]: [WARNING] [pool www] child 4321 exited on signal 11 (SIGSEGV - core dumped) after 120.5 seconds from start",
+			"php-fpm-exited", "www", 4321, 120.5,
+		},
+		{
+			"php-fpm[900]: [WARNING] [pool www] child 4322, script '/var/www/index.php' (request: \"GET /index.php\") executing too slow (5.123 sec), logging",
+			"php-fpm-slow-request", "www", 4322, 5.123,
+		},
+		{
+			"php-fpm7.4[900]: [NOTICE] fpm is running, pid 900",
+			"php-fpm-notice", "", 900, -1,
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*PhpFpmLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't a php-fpm event", test.message)
+			continue
+		}
+
+		if event.Summary() != test.summary ||
+			event.Pool != test.pool ||
+			event.Pid != test.pid ||
+			event.Duration != test.duration {
+			t.Errorf("%q parsed as %+v", test.message, event)
+		}
+	}
+}
+
+func TestPhpFpmExitSignal(t *testing.T) {
+	message := "php-fpm[900]: [WARNING] [pool www] child 4321 exited on signal 11 (SIGSEGV - core dumped) after 120.5 seconds from start"
+	event := parseTestLine(t, 0, message).(*PhpFpmLogEvent)
+
+	if event.Signal != 11 || event.ExitCode != 0 {
+		t.Errorf("Signal, ExitCode = %d, %d, want 11, 0", event.Signal, event.ExitCode)
+	}
+}
+
+// TestPhpFpmSlowlog checks that a slow log entry is put back together from
+// its lines.
+func TestPhpFpmSlowlog(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	released := []LogEventInterface{}
+
+	for _, line := range []string{
+		"php-fpm-slow: [17-Oct-2026 10:00:00]  [pool www] pid 4322",
+		"php-fpm-slow: script_filename = /var/www/index.php",
+		"php-fpm-slow: [0x00007f3b2c8e4f10] curl_exec() /var/www/lib/Http.php:52",
+		"php-fpm-slow: [0x00007f3b2c8e4e80] get() /var/www/index.php:10",
+	} {
+		released = append(released, assembler.Add(parseTestLine(t, 0, line))...)
+	}
+
+	released = append(released, assembler.Flush()...)
+
+	if len(released) != 1 {
+		t.Fatalf("released %q, want one slow log entry", summariesOf(released))
+	}
+
+	event := released[0].(*PhpFpmLogEvent)
+
+	want := []PhpFpmStackFrame{
+		{Function: "curl_exec", File: "/var/www/lib/Http.php", Line: 52},
+		{Function: "get", File: "/var/www/index.php", Line: 10},
+	}
+
+	if event.Summary() != "php-fpm-slowlog" ||
+		event.Pid != 4322 ||
+		event.Script != "/var/www/index.php" ||
+		!reflect.DeepEqual(event.StackTrace, want) {
+		t.Errorf("slow log entry = %+v", event)
+	}
+}
@@ -6,20 +6,61 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 	"text/tabwriter"
-	"time"
 
 	ct "github.com/daviddengcn/go-colortext"
 	settings "github.com/lovek323/bclog/settings"
 )
 
+var phpStackTraceRegexp = regexp.MustCompile("^(?P<source>.*?): PHP Stack trace:")
+var phpSqlErrorRegexp = regexp.MustCompile(
+	"^php: SQL Error \\(store_(?P<storeId>[0-9]{1,})\\): " +
+		"(?P<content>.{1,}) in (?P<file>[^ ]{1,}) " +
+		"on line (?P<line>[0-9]{1,})",
+)
+var phpErrorRegexp = regexp.MustCompile(
+	"^(?P<source>.*?): PHP (?P<level>.*?):  (?P<content>.{1,}) " +
+		"in (?P<file>[^ ]{1,}) " +
+		"on line (?P<line>[0-9]{1,})",
+)
+var phpStackTraceLineRegexp = regexp.MustCompile(
+	"^(?P<source>.*?): PHP[ ]{1,}(?P<number>[0-9]{1,})\\. " +
+		"(?P<method>.*)\\((?P<parameters>.{0,})\\) " +
+		"(?P<file>[^ ]*)(?P<eval> : eval\\(\\)'d code|):(?P<line>[0-9]{1,})$",
+)
+var phpEvalStackTraceLineRegexp = regexp.MustCompile(
+	"^(?P<source>.*?): PHP[ ]{1,}(?P<number>[0-9]{1,})\\. " +
+		"(?P<method>[^ ]*) (?P<file>[^ ]*)\\((?P<line>[0-9]{1,})\\) " +
+		": eval\\(\\)'d code:(?P<evalLine>[0-9]{1,})$",
+)
+var phpUncaughtExceptionRegexp = regexp.MustCompile(
+	"(?s)^(?:Uncaught |Next )" +
+		"(?:exception '(?P<type5>[^']+)' with message '(?P<message5>.*?)'|" +
+		"(?P<type>[A-Za-z0-9_\\\\]+): (?P<message>.*?))" +
+		" in (?P<file>[^ \n]+):(?P<line>[0-9]{1,})" +
+		"(?:\nStack trace:\n(?P<stackTrace>.*)|\\s*)$",
+)
+var phpExceptionFrameRegexp = regexp.MustCompile(
+	"^#(?P<number>[0-9]{1,}) " +
+		"(?:(?P<file>[^(]+)\\((?P<line>[0-9]{1,})\\): |\\[internal function\\]: )?" +
+		"(?P<method>.*)$",
+)
+var phpThrownRegexp = regexp.MustCompile("\n\\s*thrown\\s*$")
+var phpNextExceptionRegexp = regexp.MustCompile("\n+Next ")
+
 type PhpLogEvent struct {
-	SyslogTime       time.Time
+	SyslogHeader
+	Tag              string
 	LogLevel         string
 	Content          string
 	File             string
 	Line             int
 	StackTraceEvents []PhpStackTraceLogEvent
+
+	// Previous is the exception that caused this one, for chained
+	// exceptions.
+	Previous *PhpLogEvent
 }
 
 func (e *PhpLogEvent) AddStackTraceEvent(stackTraceEvent *PhpStackTraceLogEvent) {
@@ -46,15 +87,23 @@ func (e *PhpLogEvent) PrintLine(index int) {
 	case "SQL Error":
 		background = ct.Red
 		break
-	case "Strict standards":
+	case "Recoverable fatal error":
+		background = ct.Red
+		break
+	case "Strict standards", "Strict Standards":
+		background = ct.None
+		break
+	case "Deprecated":
 		background = ct.None
 		break
 	default:
-		log.Fatalf(e.LogLevel)
+		// Levels from newer versions of PHP are shown like notices.
+		background = ct.None
 	}
 
 	fmt.Printf("[%d]  ", index)
 	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
 	ct.ChangeColor(ct.Yellow, false, background, false)
 	fmt.Print("php  ")
 	ct.ChangeColor(ct.Cyan, false, background, false)
@@ -76,21 +125,33 @@ func (e *PhpLogEvent) PrintFull() {
 		e.SyslogTime.Format("2006-01-02 15:04:05"),
 	)
 
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
 	fmt.Fprintf(writer, "LogLevel:\t%s\n", e.LogLevel)
-	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
-	fmt.Fprintf(writer, "File:\t%s\n", e.File)
-	fmt.Fprintf(writer, "Line:\t%d\n", e.Line)
+
+	e.printException(writer, "")
+
+	fmt.Printf("\n---------- PHP LOG EVENT ----------\n")
+}
+
+// printException prints the content, location and stack trace of the event,
+// followed by those of its previous exceptions, each indented further than
+// the one it caused.
+func (e *PhpLogEvent) printException(writer *tabwriter.Writer, indent string) {
+	fmt.Fprintf(writer, "%sContent:\t%s\n", indent, e.Content)
+	fmt.Fprintf(writer, "%sFile:\t%s\n", indent, e.File)
+	fmt.Fprintf(writer, "%sLine:\t%d\n", indent, e.Line)
 
 	writer.Flush()
 
 	ct.ChangeColor(ct.White, true, ct.None, false)
-	fmt.Print("\nStack trace\n")
+	fmt.Printf("\n%sStack trace\n", indent)
 	ct.ResetColor()
 
 	for _, phpStackTraceLogEvent := range e.StackTraceEvents {
 		fmt.Fprintf(
 			writer,
-			"%d.\t%s\t%s\t%d\n",
+			"%s%d.\t%s\t%s\t%d\n",
+			indent,
 			phpStackTraceLogEvent.Number,
 			phpStackTraceLogEvent.Method,
 			phpStackTraceLogEvent.File,
@@ -100,17 +161,19 @@ func (e *PhpLogEvent) PrintFull() {
 
 	writer.Flush()
 
-	fmt.Printf("\n---------- PHP LOG EVENT ----------\n")
+	if e.Previous != nil {
+		ct.ChangeColor(ct.White, true, ct.None, false)
+		fmt.Printf("\n%sCaused by\n", indent)
+		ct.ResetColor()
+
+		e.Previous.printException(writer, indent+"    ")
+	}
 }
 
 func (e *PhpLogEvent) Summary() string {
 	return "php-" + e.LogLevel
 }
 
-func (e *PhpLogEvent) GetSyslogTime() time.Time {
-	return e.SyslogTime
-}
-
 func (e *PhpLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
 	contentPatterns := settings_.GetPhpSuppressContentRegexes()
 
@@ -133,8 +196,11 @@ func (e *PhpLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
 	return false
 }
 
+// PhpStackTraceLogEvent is one frame of a stack trace, or the "PHP Stack
+// trace:" line that starts one, which has a Number of 0.
 type PhpStackTraceLogEvent struct {
-	SyslogTime time.Time
+	SyslogHeader
+	Tag        string
 	Number     int
 	Method     string
 	Parameters string
@@ -142,13 +208,64 @@ type PhpStackTraceLogEvent struct {
 	Line       int
 }
 
-func (e *PhpStackTraceLogEvent) GetSyslogTime() time.Time {
-	return e.SyslogTime
+// assemble holds the error back until its stack trace is complete.
+func (e *PhpLogEvent) assemble(a *Assembler) []LogEventInterface {
+	key := "php " + e.Source + " " + e.Tag
+
+	complete := a.release(key)
+	a.hold(key, e)
+
+	return complete
+}
+
+// assemble adds the frame to the error its process logged last.
+func (e *PhpStackTraceLogEvent) assemble(a *Assembler) []LogEventInterface {
+	key := "php " + e.Source + " " + e.Tag
+	pending, ok := a.pending[key]
+
+	if ok && e.SyslogTime.Sub(pending.last) > a.Window {
+		return append(a.release(key), e.orphan()...)
+	}
+
+	if !ok {
+		return e.orphan()
+	}
+
+	phpLogEvent := pending.event.(*PhpLogEvent)
+
+	// A second "PHP Stack trace:" line belongs to an error we missed.
+	if e.isHeader() && len(phpLogEvent.StackTraceEvents) > 0 {
+		return append(a.release(key), e.orphan()...)
+	}
+
+	if !e.isHeader() {
+		phpLogEvent.AddStackTraceEvent(e)
+	}
+
+	pending.last = e.SyslogTime
+
+	return nil
+}
+
+// orphan handles trace lines whose error isn't in the log (e.g., because it
+// was logged before the first line read). Frames are kept as events of their
+// own, while the "PHP Stack trace:" line carries nothing worth keeping.
+func (e *PhpStackTraceLogEvent) orphan() []LogEventInterface {
+	if e.isHeader() {
+		return nil
+	}
+
+	return []LogEventInterface{e}
+}
+
+func (e *PhpStackTraceLogEvent) isHeader() bool {
+	return e.Number == 0
 }
 
 func (e *PhpStackTraceLogEvent) PrintLine(index int) {
 	fmt.Printf("[%d]  ", index)
 	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
 	ct.ChangeColor(ct.Yellow, false, ct.None, false)
 	fmt.Print("php-stack-trace  ")
 	ct.ChangeColor(ct.Cyan, false, ct.None, false)
@@ -170,32 +287,32 @@ func (e *PhpStackTraceLogEvent) Suppress(
 	return settings_.GetPhpSuppressStackTraces()
 }
 
+func init() {
+	RegisterParser(Parser{
+		Name:     "php",
+		Priority: 60,
+		Match:    NewPhpLogEvent,
+	})
+}
+
 func NewPhpLogEvent(
-	syslogTime time.Time,
-	source string,
+	header SyslogHeader,
 	message string,
 ) LogEventInterface {
-	re := regexp.MustCompile("^(?P<source>.*?): PHP Stack trace:")
-
-	matches := re.FindStringSubmatch(message)
+	matches := phpStackTraceRegexp.FindStringSubmatch(message)
 
 	if matches != nil {
 		return &PhpStackTraceLogEvent{
-			SyslogTime: syslogTime,
-			Number:     0,
-			Method:     "",
-			File:       "",
-			Line:       0,
+			SyslogHeader: header,
+			Tag:          matches[1],
+			Number:       0,
+			Method:       "",
+			File:         "",
+			Line:         0,
 		}
 	}
 
-	re = regexp.MustCompile(
-		"^php: SQL Error \\(store_(?P<storeId>[0-9]{1,})\\): " +
-			"(?P<content>.{1,}) in (?P<file>[^ ]{1,}) " +
-			"on line (?P<line>[0-9]{1,})",
-	)
-
-	matches = re.FindStringSubmatch(message)
+	matches = phpSqlErrorRegexp.FindStringSubmatch(message)
 
 	if matches != nil {
 		line, err := strconv.ParseInt(matches[4], 10, 32)
@@ -205,21 +322,16 @@ func NewPhpLogEvent(
 		}
 
 		return &PhpLogEvent{
-			SyslogTime: syslogTime,
-			LogLevel:   "SQL Error",
-			Content:    matches[2] + " (store ID: " + matches[1] + ")",
-			File:       matches[3],
-			Line:       int(line),
+			SyslogHeader: header,
+			Tag:          "php",
+			LogLevel:     "SQL Error",
+			Content:      matches[2] + " (store ID: " + matches[1] + ")",
+			File:         matches[3],
+			Line:         int(line),
 		}
 	}
 
-	re = regexp.MustCompile(
-		"^(?P<source>.*?): PHP (?P<level>.*?):  (?P<content>.{1,}) " +
-			"in (?P<file>[^ ]{1,}) " +
-			"on line (?P<line>[0-9]{1,})",
-	)
-
-	matches = re.FindStringSubmatch(message)
+	matches = phpErrorRegexp.FindStringSubmatch(message)
 
 	if matches != nil {
 		line, err := strconv.ParseInt(matches[5], 10, 32)
@@ -233,85 +345,23 @@ func NewPhpLogEvent(
 		file := matches[4]
 
 		event := PhpLogEvent{
-			SyslogTime: syslogTime,
-			LogLevel:   logLevel,
-			Content:    content,
-			File:       file,
-			Line:       int(line),
+			SyslogHeader: header,
+			Tag:          matches[1],
+			LogLevel:     logLevel,
+			Content:      content,
+			File:         file,
+			Line:         int(line),
 		}
 
-		re = regexp.MustCompile(
-			"^Uncaught exception '(?P<type>.*?)' with message " +
-				"'(?P<message>.*?)' in (?P<firstFile>[^ ]{1,}):" +
-				"(?P<firstLine>[0-9]{1,})(?P<stackTrace>.*)",
-		)
-		matches = re.FindStringSubmatch(content)
-
-		if matches != nil {
-			event.Content = matches[1] + ": " + matches[2]
-
-			re = regexp.MustCompile(
-				"#(?P<number>[0-9]{1,})#(?P<index>[0-9]{1,}) " +
-					"(?P<file>[^ ]{1,})\\((?P<line>[0-9]{1,})\\): (?P<details>.*?)",
-			)
-
-			stackTrace := matches[5]
-			matches_ := re.FindAllStringSubmatch(stackTrace, -1)
-
-			for _, match := range matches_ {
-				// number := match[1]
-				index, err := strconv.ParseInt(match[2], 10, 32)
-
-				if err != nil {
-					log.Fatalf(
-						"Could not parse number: %s (%s)\n",
-						matches[2],
-						err,
-					)
-				}
-
-				file := match[3]
-
-				line, err := strconv.ParseInt(match[4], 10, 32)
-
-				if err != nil {
-					log.Fatalf(
-						"Could not parse number: %s (%s)\n",
-						matches[4],
-						err,
-					)
-				}
-
-				details := match[5]
-
-				stackTraceEvent := &PhpStackTraceLogEvent{
-					SyslogTime: event.SyslogTime,
-					Number:     int(index),
-					Method:     details,
-					Parameters: "",
-					File:       file,
-					Line:       int(line),
-				}
-
-				event.AddStackTraceEvent(stackTraceEvent)
-			}
-		}
+		parseUncaughtException(&event, content)
 
 		return &event
 	}
 
 	// Search for a stack trace.
-	re = regexp.MustCompile(
-		"^(?P<source>.*?): PHP[ ]{1,}(?P<number>[0-9]{1,})\\. " +
-			"(?P<method>.*)\\((?P<parameters>.{0,})\\) " +
-			"(?P<file>[^ ]*)(?P<eval> : eval\\(\\)'d code|):(?P<line>[0-9]{1,})$",
-	)
-
-	matches = re.FindStringSubmatch(message)
+	matches = phpStackTraceLineRegexp.FindStringSubmatch(message)
 
 	if matches != nil {
-		// source := matches[1]
-
 		number, err := strconv.ParseInt(matches[2], 10, 32)
 
 		if err != nil {
@@ -329,23 +379,18 @@ func NewPhpLogEvent(
 		}
 
 		return &PhpStackTraceLogEvent{
-			SyslogTime: syslogTime,
-			Number:     int(number),
-			Method:     method,
-			Parameters: parameters,
-			File:       file,
-			Line:       int(line),
+			SyslogHeader: header,
+			Tag:          matches[1],
+			Number:       int(number),
+			Method:       method,
+			Parameters:   parameters,
+			File:         file,
+			Line:         int(line),
 		}
 	}
 
 	// Search for a stack trace with eval()'d code.
-	re = regexp.MustCompile(
-		"^(?P<source>.*?): PHP[ ]{1,}(?P<number>[0-9]{1,})\\. " +
-			"(?P<method>[^ ]*) (?P<file>[^ ]*)\\((?P<line>[0-9]{1,})\\) " +
-			": eval\\(\\)'d code:(?P<evalLine>[0-9]{1,})$",
-	)
-
-	matches = re.FindStringSubmatch(message)
+	matches = phpEvalStackTraceLineRegexp.FindStringSubmatch(message)
 
 	if matches != nil {
 		number, err := strconv.ParseInt(matches[2], 10, 32)
@@ -361,13 +406,103 @@ func NewPhpLogEvent(
 		}
 
 		return &PhpStackTraceLogEvent{
-			SyslogTime: syslogTime,
-			Number:     int(number),
-			Method:     matches[3],
-			File:       matches[4],
-			Line:       int(line),
+			SyslogHeader: header,
+			Tag:          matches[1],
+			Number:       int(number),
+			Method:       matches[3],
+			File:         matches[4],
+			Line:         int(line),
 		}
 	}
 
 	return nil
 }
+
+// parseUncaughtException fills in the exception, stack trace and previous
+// exceptions of an uncaught exception error from its content, which can be in
+// either the PHP 5 form:
+//
+//	Uncaught exception 'X' with message 'm' in f:1
+//
+// or the PHP 7+ one:
+//
+//	Uncaught X: m in f:1
+//
+// Both are followed by a stack trace, and by "Next" and another exception
+// for each exception in the chain, the last of which is the one that went
+// uncaught. Syslog escapes the newlines between them as #012.
+func parseUncaughtException(event *PhpLogEvent, content string) bool {
+	content = strings.Replace(content, "#012", "\n", -1)
+
+	if !strings.HasPrefix(content, "Uncaught ") {
+		return false
+	}
+
+	content = phpThrownRegexp.ReplaceAllString(content, "")
+
+	var previous *PhpLogEvent
+
+	for _, segment := range phpNextExceptionRegexp.Split(content, -1) {
+		if previous != nil {
+			segment = "Next " + segment
+		}
+
+		matches := phpUncaughtExceptionRegexp.FindStringSubmatch(segment)
+
+		if matches == nil {
+			return false
+		}
+
+		exception := &PhpLogEvent{
+			SyslogHeader: event.SyslogHeader,
+			Tag:          event.Tag,
+			LogLevel:     event.LogLevel,
+			Content:      matches[1] + matches[3] + ": " + matches[2] + matches[4],
+			File:         matches[5],
+			Previous:     previous,
+		}
+
+		exception.Line, _ = strconv.Atoi(matches[6])
+
+		for _, frame := range strings.Split(matches[7], "\n") {
+			if stackTraceEvent := parseStackTraceFrame(exception, frame); stackTraceEvent != nil {
+				exception.AddStackTraceEvent(stackTraceEvent)
+			}
+		}
+
+		previous = exception
+	}
+
+	// The error's file and line (from "thrown in") are already the last
+	// exception's.
+	event.Content = previous.Content
+	event.StackTraceEvents = previous.StackTraceEvents
+	event.Previous = previous.Previous
+
+	return true
+}
+
+// parseStackTraceFrame parses one "#n file(line): call" line of an exception's
+// stack trace.
+func parseStackTraceFrame(
+	event *PhpLogEvent,
+	frame string,
+) *PhpStackTraceLogEvent {
+	matches := phpExceptionFrameRegexp.FindStringSubmatch(strings.TrimSpace(frame))
+
+	if matches == nil {
+		return nil
+	}
+
+	number, _ := strconv.Atoi(matches[1])
+	line, _ := strconv.Atoi(matches[3])
+
+	return &PhpStackTraceLogEvent{
+		SyslogHeader: event.SyslogHeader,
+		Tag:          event.Tag,
+		Number:       number,
+		Method:       matches[4],
+		File:         matches[2],
+		Line:         line,
+	}
+}
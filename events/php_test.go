@@ -0,0 +1,167 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testPhpFrame struct {
+	number int
+	method string
+	file   string
+	line   int
+}
+
+func testPhpFrames(event *PhpLogEvent) []testPhpFrame {
+	frames := []testPhpFrame{}
+
+	for _, frame := range event.StackTraceEvents {
+		frames = append(frames, testPhpFrame{frame.Number, frame.Method, frame.File, frame.Line})
+	}
+
+	return frames
+}
+
+func parseTestPhpLine(t *testing.T, message string) *PhpLogEvent {
+	event, ok := parseTestLine(t, 0, message).(*PhpLogEvent)
+
+	if !ok {
+		t.Fatalf("%q isn't a PHP event", message)
+	}
+
+	return event
+}
+
+func TestPhpLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message string
+		level   string
+		content string
+		file    string
+		line    int
+	}{
+		{
+			"php: PHP Warning:  Undefined array key \"sku\" in /var/www/app/Product.php on line 88",
+			"Warning", "Undefined array key \"sku\"", "/var/www/app/Product.php", 88,
+		},
+		{
+			"php: PHP Deprecated:  strlen(): Passing null to parameter #1 ($string) of type string is deprecated in /var/www/app/Cart.php on line 120",
+			"Deprecated",
+			"strlen(): Passing null to parameter #1 ($string) of type string is deprecated",
+			"/var/www/app/Cart.php", 120,
+		},
+		{
+			"php: PHP Recoverable fatal error:  Object of class Money could not be converted to string in /var/www/app/Order.php on line 31",
+			"Recoverable fatal error",
+			"Object of class Money could not be converted to string",
+			"/var/www/app/Order.php", 31,
+		},
+	} {
+		event := parseTestPhpLine(t, test.message)
+
+		if event.LogLevel != test.level ||
+			event.Content != test.content ||
+			event.File != test.file ||
+			event.Line != test.line {
+			t.Errorf("%q parsed as %+v", test.message, event)
+		}
+
+		if summary := event.Summary(); summary != "php-"+test.level {
+			t.Errorf("%q summary = %q", test.message, summary)
+		}
+
+		// Every level has to be printable, rather than stopping bclog.
+		event.PrintLine(1)
+	}
+}
+
+func TestPhpUncaughtException5(t *testing.T) {
+	event := parseTestPhpLine(
+		t,
+		"php: PHP Fatal error:  Uncaught exception 'RuntimeException' with message 'Cart is empty' in /var/www/app/Cart.php:42#012Stack trace:#012#0 /var/www/app/Checkout.php(17): Cart->total()#012#1 {main}#012  thrown in /var/www/app/Cart.php on line 42",
+	)
+
+	if event.Content != "RuntimeException: Cart is empty" ||
+		event.File != "/var/www/app/Cart.php" ||
+		event.Line != 42 ||
+		event.Previous != nil {
+		t.Errorf("event = %+v", event)
+	}
+
+	want := []testPhpFrame{
+		{0, "Cart->total()", "/var/www/app/Checkout.php", 17},
+		{1, "{main}", "", 0},
+	}
+
+	if frames := testPhpFrames(event); !reflect.DeepEqual(frames, want) {
+		t.Errorf("frames = %v, want %v", frames, want)
+	}
+}
+
+func TestPhpUncaughtException7(t *testing.T) {
+	event := parseTestPhpLine(
+		t,
+		"php: PHP Fatal error:  Uncaught Error: Call to undefined function legacy_price() in /var/www/app/index.php:5#012Stack trace:#012#0 [internal function]: {closure}()#012#1 {main}#012  thrown in /var/www/app/index.php on line 5",
+	)
+
+	if event.Content != "Error: Call to undefined function legacy_price()" ||
+		event.File != "/var/www/app/index.php" ||
+		event.Line != 5 {
+		t.Errorf("event = %+v", event)
+	}
+
+	want := []testPhpFrame{
+		{0, "{closure}()", "", 0},
+		{1, "{main}", "", 0},
+	}
+
+	if frames := testPhpFrames(event); !reflect.DeepEqual(frames, want) {
+		t.Errorf("frames = %v, want %v", frames, want)
+	}
+}
+
+// TestPhpUncaughtExceptionNext checks that the exception that went uncaught is
+// the event, and the one it was thrown for is its Previous.
+func TestPhpUncaughtExceptionNext(t *testing.T) {
+	event := parseTestPhpLine(
+		t,
+		"php: PHP Fatal error:  Uncaught PDOException: SQLSTATE[HY000] [2002] Connection refused in /var/www/app/Db.php:10#012Stack trace:#012#0 /var/www/app/Db.php(10): PDO->__construct('mysql:host=db')#012#1 {main}#012#012Next App\\DatabaseException: Could not connect in /var/www/app/Db.php:12#012Stack trace:#012#0 /var/www/app/index.php(3): App\\Db->connect()#012#1 {main}#012  thrown in /var/www/app/Db.php on line 12",
+	)
+
+	if event.Content != "App\\DatabaseException: Could not connect" ||
+		event.File != "/var/www/app/Db.php" ||
+		event.Line != 12 {
+		t.Errorf("event = %+v", event)
+	}
+
+	want := []testPhpFrame{
+		{0, "App\\Db->connect()", "/var/www/app/index.php", 3},
+		{1, "{main}", "", 0},
+	}
+
+	if frames := testPhpFrames(event); !reflect.DeepEqual(frames, want) {
+		t.Errorf("frames = %v, want %v", frames, want)
+	}
+
+	previous := event.Previous
+
+	if previous == nil {
+		t.Fatal("Previous = nil")
+	}
+
+	if previous.Content != "PDOException: SQLSTATE[HY000] [2002] Connection refused" ||
+		previous.File != "/var/www/app/Db.php" ||
+		previous.Line != 10 ||
+		previous.Previous != nil {
+		t.Errorf("Previous = %+v", previous)
+	}
+
+	want = []testPhpFrame{
+		{0, "PDO->__construct('mysql:host=db')", "/var/www/app/Db.php", 10},
+		{1, "{main}", "", 0},
+	}
+
+	if frames := testPhpFrames(previous); !reflect.DeepEqual(frames, want) {
+		t.Errorf("previous frames = %v, want %v", frames, want)
+	}
+}
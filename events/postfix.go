@@ -0,0 +1,405 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+var postfixRegexp = regexp.MustCompile(
+	"^(?P<tag>postfix[^/\\[: ]*/(?P<process>[^\\[: ]+))" +
+		"(?:\\[(?P<pid>[0-9]{1,})\\])?: (?P<content>.*)$",
+)
+var postfixQueueIdRegexp = regexp.MustCompile(
+	"^(?P<queueId>[0-9A-F]{6,}|" +
+		"[0-9B-DF-HJ-NP-TV-Zb-df-hj-np-tv-z]{8,}z[0-9B-DF-HJ-NP-TV-Zb-df-hj-np-tv-z]+" +
+		"): (?P<content>.*)$",
+)
+var postfixLevelRegexp = regexp.MustCompile("^(?P<level>warning|error|fatal|panic): ")
+var postfixAttributeRegexp = regexp.MustCompile(
+	"(?:^|,? )(?P<name>[a-z-]+)=(?P<value><[^>]*>|[^, ]*)",
+)
+var postfixSizeRegexp = regexp.MustCompile("(?:^|, )size=(?P<size>[0-9]{1,})")
+
+// PostfixLogEvent is a line logged by one of postfix's daemons, e.g.,
+// postfix/smtp. Lines about a queued message start with its queue ID, and the
+// Assembler links them all to the same Message, so any of them shows what
+// happened to the message as a whole. Status is set for delivery attempts and
+// is usually "sent", "deferred", "bounced" or "expired".
+type PostfixLogEvent struct {
+	SyslogHeader
+	Tag       string
+	Process   string
+	Pid       int
+	QueueId   string
+	LogLevel  string
+	From      string
+	To        string
+	Relay     string
+	Delay     float64 // seconds, -1 if not logged
+	Dsn       string
+	Status    string
+	Response  string
+	MessageId string
+	Content   string
+	Message   *PostfixMessage
+}
+
+// postfixMessageAge is how long a message is remembered after the last line
+// about it. It is postfix's default maximal_queue_lifetime, after which a
+// message that couldn't be delivered is bounced and removed.
+const postfixMessageAge = 5 * 24 * time.Hour
+
+// PostfixMessage is everything logged about one queued message.
+type PostfixMessage struct {
+	QueueId    string
+	MessageId  string
+	From       string
+	Size       int
+	Recipients []*PostfixRecipient
+	Removed    bool
+
+	last time.Time // log time of the latest line about the message
+}
+
+// PostfixRecipient is the latest delivery attempt to one recipient of a
+// message.
+type PostfixRecipient struct {
+	To       string
+	Relay    string
+	Delay    float64
+	Dsn      string
+	Status   string
+	Response string
+}
+
+func (e *PostfixLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	switch {
+	case e.Status == "bounced" || e.Status == "expired" ||
+		e.LogLevel == "fatal" || e.LogLevel == "panic":
+		background = ct.Red
+	case e.Status == "deferred":
+		background = ct.Magenta
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("postfix  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s-%s  ", e.Process, e.QueueId)
+	ct.ChangeColor(ct.None, false, background, false)
+	fmt.Printf("%s\n", e.Content)
+	ct.ResetColor()
+}
+
+// PrintMessageLine prints a line describing the event's message as a whole:
+// who it's from and what became of each recipient.
+func (e *PostfixLogEvent) PrintMessageLine(index int) {
+	message := e.Message
+	recipients := []string{}
+
+	for _, recipient := range message.Recipients {
+		recipients = append(
+			recipients,
+			fmt.Sprintf("%s (%s)", recipient.To, recipient.Status),
+		)
+	}
+
+	background := ct.None
+
+	for _, recipient := range message.Recipients {
+		if recipient.Status != "sent" {
+			background = ct.Red
+		}
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("postfix  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s  ", message.QueueId)
+	ct.ChangeColor(ct.None, false, background, false)
+	fmt.Printf("%s -> %s\n", message.From, strings.Join(recipients, ", "))
+	ct.ResetColor()
+}
+
+func (e *PostfixLogEvent) PrintFull() {
+	fmt.Printf("\n---------- POSTFIX LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Tag:\t%s\n", e.Tag)
+	fmt.Fprintf(writer, "Pid:\t%d\n", e.Pid)
+	fmt.Fprintf(writer, "QueueId:\t%s\n", e.QueueId)
+	fmt.Fprintf(writer, "LogLevel:\t%s\n", e.LogLevel)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	writer.Flush()
+
+	if e.Message != nil {
+		ct.ChangeColor(ct.White, true, ct.None, false)
+		fmt.Print("\nMessage\n")
+		ct.ResetColor()
+
+		fmt.Fprintf(writer, "MessageId:\t%s\n", e.Message.MessageId)
+		fmt.Fprintf(writer, "From:\t%s\n", e.Message.From)
+		fmt.Fprintf(writer, "Size:\t%d\n", e.Message.Size)
+		fmt.Fprintf(writer, "Removed:\t%t\n", e.Message.Removed)
+
+		for _, recipient := range e.Message.Recipients {
+			fmt.Fprintf(
+				writer,
+				"To:\t%s\t%s\t%s\t%.2fs\t%s\n",
+				recipient.To,
+				recipient.Status,
+				recipient.Relay,
+				recipient.Delay,
+				recipient.Response,
+			)
+		}
+
+		writer.Flush()
+	}
+
+	fmt.Printf("---------- POSTFIX LOG EVENT ----------\n\n")
+}
+
+func (e *PostfixLogEvent) Summary() string {
+	if e.Status != "" {
+		return "postfix-" + e.Status
+	}
+
+	if e.LogLevel != "" {
+		return "postfix-" + e.LogLevel
+	}
+
+	return "postfix-" + e.Process
+}
+
+func (e *PostfixLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	if e.Status != "" {
+		for _, status := range settings_.GetPostfixSuppressStatuses() {
+			if e.Status == status {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if e.LogLevel != "" {
+		return false
+	}
+
+	for _, process := range settings_.GetPostfixSuppressProcesses() {
+		if e.Process == process {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetField returns the queue_id, process, status and relay of the event, the
+// recipient (to) of a delivery attempt, and the sender (from) and message_id
+// of the message the event is about.
+func (e *PostfixLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "queue_id":
+		return e.QueueId, true
+	case "process":
+		return e.Process, true
+	case "status":
+		return e.Status, true
+	case "relay":
+		return e.Relay, true
+	case "to":
+		return e.To, true
+	}
+
+	if e.Message == nil {
+		return "", false
+	}
+
+	switch name {
+	case "from":
+		return e.Message.From, true
+	case "message_id":
+		return e.Message.MessageId, true
+	}
+
+	return "", false
+}
+
+// add records what the event says about the message.
+func (m *PostfixMessage) add(event *PostfixLogEvent) {
+	if event.From != "" {
+		m.From = event.From
+	}
+
+	if event.MessageId != "" {
+		m.MessageId = event.MessageId
+	}
+
+	if event.Content == "removed" {
+		m.Removed = true
+	}
+
+	if matches := postfixSizeRegexp.FindStringSubmatch(event.Content); matches != nil {
+		m.Size, _ = strconv.Atoi(matches[1])
+	}
+
+	if event.To == "" || event.Status == "" {
+		return
+	}
+
+	var recipient *PostfixRecipient
+
+	for _, existing := range m.Recipients {
+		if existing.To == event.To {
+			recipient = existing
+		}
+	}
+
+	if recipient == nil {
+		recipient = &PostfixRecipient{To: event.To}
+		m.Recipients = append(m.Recipients, recipient)
+	}
+
+	recipient.Relay = event.Relay
+	recipient.Delay = event.Delay
+	recipient.Dsn = event.Dsn
+	recipient.Status = event.Status
+	recipient.Response = event.Response
+}
+
+func (m *PostfixMessage) stale(newest time.Time) bool {
+	return newest.Sub(m.last) > postfixMessageAge
+}
+
+// assemble links a line about a queued message to the message with its queue
+// ID. Queue IDs are reused, so a message is forgotten once postfix has removed
+// it.
+func (e *PostfixLogEvent) assemble(a *Assembler) []LogEventInterface {
+	if e.QueueId == "" {
+		return []LogEventInterface{e}
+	}
+
+	key := "postfix " + e.Source + " " + e.QueueId
+	message, ok := a.links[key].(*PostfixMessage)
+
+	if !ok {
+		message = &PostfixMessage{QueueId: e.QueueId}
+		a.links[key] = message
+	}
+
+	message.add(e)
+	message.last = e.SyslogTime
+	e.Message = message
+
+	if message.Removed {
+		delete(a.links, key)
+	}
+
+	return []LogEventInterface{e}
+}
+
+func init() {
+	RegisterParser(Parser{
+		Name:     "postfix",
+		Priority: 39,
+		Match:    NewPostfixLogEvent,
+	})
+}
+
+// NewPostfixLogEvent parses lines from processes tagged postfix/<daemon> (or
+// postfix-<instance>/<daemon>). Queue IDs are either the short hexadecimal
+// kind or the long kind postfix uses with enable_long_queue_ids.
+func NewPostfixLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	matches := postfixRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return nil
+	}
+
+	event := &PostfixLogEvent{
+		SyslogHeader: header,
+		Tag:          matches[1],
+		Process:      matches[2],
+		Delay:        -1,
+		Content:      matches[4],
+	}
+
+	event.Pid, _ = strconv.Atoi(matches[3])
+
+	if matches = postfixQueueIdRegexp.FindStringSubmatch(event.Content); matches != nil {
+		event.QueueId = matches[1]
+		event.Content = matches[2]
+	}
+
+	if matches = postfixLevelRegexp.FindStringSubmatch(event.Content); matches != nil {
+		event.LogLevel = matches[1]
+	}
+
+	if event.QueueId == "" {
+		return event
+	}
+
+	// Attributes are listed as "name=value, name=value" (pickup leaves out
+	// the commas), optionally followed by a comment in brackets, which for
+	// deliveries is the response.
+	attributes := event.Content
+	comment := ""
+
+	if i := strings.Index(attributes, " ("); i >= 0 {
+		comment = strings.TrimSuffix(attributes[i+2:], ")")
+		attributes = attributes[:i]
+	}
+
+	for _, attribute := range postfixAttributeRegexp.FindAllStringSubmatch(attributes, -1) {
+		value := strings.TrimSuffix(strings.TrimPrefix(attribute[2], "<"), ">")
+
+		switch attribute[1] {
+		case "from":
+			event.From = value
+		case "to":
+			event.To = value
+		case "relay":
+			event.Relay = value
+		case "delay":
+			event.Delay, _ = strconv.ParseFloat(value, 64)
+		case "dsn":
+			event.Dsn = value
+		case "status":
+			event.Status = value
+			event.Response = comment
+		case "message-id":
+			event.MessageId = value
+		}
+	}
+
+	return event
+}
@@ -0,0 +1,130 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPostfixLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message string
+		summary string
+		queueId string
+		fields  map[string]string
+	}{
+		{
+			"postfix/smtp[2345]: 4BtCYq0pxlzB7W: to=<customer@example.com>, relay=mx.example.com[203.0.113.5]:25, delay=1.2, delays=0.1/0/0.5/0.6, dsn=2.0.0, status=sent (250 2.0.0 OK 1697536800)",
+			"postfix-sent", "4BtCYq0pxlzB7W",
+			map[string]string{"to": "customer@example.com", "relay": "mx.example.com[203.0.113.5]:25", "status": "sent"},
+		},
+		{
+			"postfix/smtp[2345]: 3F1A2B4C5D: to=<customer@example.com>, relay=none, delay=300, delays=0/0/300/0, dsn=4.4.1, status=deferred (connect to mx.example.com[203.0.113.5]:25: Connection timed out)",
+			"postfix-deferred", "3F1A2B4C5D",
+			map[string]string{"status": "deferred", "relay": "none"},
+		},
+		{
+			"postfix/pickup[1234]: 3F1A2B4C5D: uid=33 from=<www-data>",
+			"postfix-pickup", "3F1A2B4C5D",
+			map[string]string{"process": "pickup"},
+		},
+		{
+			"postfix/smtpd[3456]: warning: hostname mail.example.net does not resolve to address 198.51.100.7",
+			"postfix-warning", "",
+			map[string]string{"process": "smtpd"},
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*PostfixLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't a postfix event", test.message)
+			continue
+		}
+
+		if event.Summary() != test.summary || event.QueueId != test.queueId {
+			t.Errorf(
+				"%q: Summary(), QueueId = %q, %q, want %q, %q",
+				test.message,
+				event.Summary(),
+				event.QueueId,
+				test.summary,
+				test.queueId,
+			)
+		}
+
+		for field, want := range test.fields {
+			if value, _ := event.GetField(field); value != want {
+				t.Errorf("%q: %s = %q, want %q", test.message, field, value, want)
+			}
+		}
+	}
+}
+
+// TestPostfixMessage checks that the lines about a message are linked to it
+// by queue ID, and that it is forgotten once removed.
+func TestPostfixMessage(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+	lines := []string{
+		"postfix/pickup[1234]: 3F1A2B4C5D: uid=33 from=<www-data>",
+		"postfix/cleanup[1235]: 3F1A2B4C5D: message-id=<20261017100000.3F1A2B4C5D@web1.example.com>",
+		"postfix/qmgr[1236]: 3F1A2B4C5D: from=<www-data@web1.example.com>, size=1234, nrcpt=1 (queue active)",
+		"postfix/smtp[2345]: 3F1A2B4C5D: to=<customer@example.com>, relay=mx.example.com[203.0.113.5]:25, delay=1.2, delays=0.1/0/0.5/0.6, dsn=2.0.0, status=sent (250 2.0.0 OK)",
+		"postfix/qmgr[1236]: 3F1A2B4C5D: removed",
+	}
+
+	var message *PostfixMessage
+
+	for i, line := range lines {
+		event := assembler.Add(parseTestLine(t, float64(i), line))[0].(*PostfixLogEvent)
+
+		if message == nil {
+			message = event.Message
+		} else if event.Message != message {
+			t.Fatalf("%q isn't linked to the message", line)
+		}
+	}
+
+	if message.From != "www-data@web1.example.com" ||
+		message.MessageId != "20261017100000.3F1A2B4C5D@web1.example.com" ||
+		message.Size != 1234 ||
+		!message.Removed {
+		t.Errorf("message = %+v", message)
+	}
+
+	want := []*PostfixRecipient{{
+		To:       "customer@example.com",
+		Relay:    "mx.example.com[203.0.113.5]:25",
+		Delay:    1.2,
+		Dsn:      "2.0.0",
+		Status:   "sent",
+		Response: "250 2.0.0 OK",
+	}}
+
+	if !reflect.DeepEqual(message.Recipients, want) {
+		t.Errorf("Recipients = %+v, want %+v", message.Recipients, want)
+	}
+
+	if countLinks(assembler, &PostfixMessage{}) != 0 {
+		t.Errorf("removed message is still remembered")
+	}
+}
+
+// TestPostfixMessageEviction checks that a message that is never removed is
+// forgotten once nothing has been logged about it for postfixMessageAge.
+func TestPostfixMessageEviction(t *testing.T) {
+	assembler := NewAssembler(2 * time.Second)
+
+	assembler.Add(parseTestLine(t, 0, "postfix/pickup[1234]: 3F1A2B4C5D: uid=33 from=<www-data>"))
+	assembler.Add(parseTestLine(t, 3600, "postfix/pickup[1234]: 4A1A2B4C5D: uid=33 from=<www-data>"))
+
+	if countLinks(assembler, &PostfixMessage{}) != 2 {
+		t.Fatalf("%d messages remembered, want 2", countLinks(assembler, &PostfixMessage{}))
+	}
+
+	later := postfixMessageAge.Seconds() + 7200
+	assembler.Add(parseTestLine(t, later, "postfix/qmgr[1236]: warning: something"))
+
+	if countLinks(assembler, &PostfixMessage{}) != 0 {
+		t.Errorf("%d messages remembered, want them evicted", countLinks(assembler, &PostfixMessage{}))
+	}
+}
@@ -5,22 +5,26 @@ import (
 	"log"
 	"regexp"
 	"strconv"
-	"time"
 
 	ct "github.com/daviddengcn/go-colortext"
 	settings "github.com/lovek323/bclog/settings"
 )
 
+var processRegexp = regexp.MustCompile(
+	"^(?P<name>.*?)\\[(?P<processId>[0-9]{1,})\\]: (?P<message>.*)$",
+)
+
 type ProcessLogEvent struct {
-	SyslogTime time.Time
-	Name       string
-	ProcessId  int
-	Content    string
+	SyslogHeader
+	Name      string
+	ProcessId int
+	Content   string
 }
 
 func (e *ProcessLogEvent) PrintLine(index int) {
 	fmt.Printf("[%d]  ", index)
 	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
 	ct.ChangeColor(ct.Yellow, false, ct.None, false)
 	fmt.Print("process  ")
 	ct.ChangeColor(ct.Cyan, false, ct.None, false)
@@ -50,56 +54,51 @@ func (e *ProcessLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
 	return false
 }
 
-func (e *ProcessLogEvent) GetSyslogTime() time.Time {
-	return e.SyslogTime
+func init() {
+	RegisterParser(Parser{
+		Name:     "process",
+		Priority: 50,
+		Match:    NewProcessLogEvent,
+	})
 }
 
 func NewProcessLogEvent(
-	syslogTime time.Time,
-	source string,
+	header SyslogHeader,
 	message string,
 ) LogEventInterface {
-	re := regexp.MustCompile(
-		"^(?P<name>.*?)\\[(?P<processId>[0-9]{1,})\\]: (?P<message>.*)$",
-	)
+	name, processId, content, ok := splitProcessMessage(message)
 
-	matches := re.FindStringSubmatch(message)
+	if !ok {
+		return nil
+	}
 
-	if matches == nil {
+	switch name {
+	case "bigcommerce_app", "ool bigcommerce_app":
+		// Anything the bigcommerce-app parser didn't take is a PHP error.
 		return nil
 	}
 
-	name := matches[1]
+	return &ProcessLogEvent{
+		SyslogHeader: header,
+		Name:         name,
+		ProcessId:    processId,
+		Content:      content,
+	}
+}
 
-	processId, err := strconv.ParseInt(matches[2], 10, 32)
+// splitProcessMessage splits a "name[pid]: content" message into its parts.
+func splitProcessMessage(message string) (string, int, string, bool) {
+	matches := processRegexp.FindStringSubmatch(message)
 
-	if err != nil {
-		log.Fatalf("Could not parse process ID: %s (%s)\n", matches[2], err)
+	if matches == nil {
+		return "", 0, "", false
 	}
 
-	content := matches[3]
-
-	switch name {
-	case "bigcommerce_app", "ool bigcommerce_app":
-		event := NewBigcommerceAppLogEvent(
-			syslogTime,
-			source,
-			int(processId),
-			content,
-		)
-
-		// This could be a PHP error as well.
-		if event != (*BigcommerceAppLogEvent)(nil) {
-			return event
-		}
+	processId, err := strconv.ParseInt(matches[2], 10, 32)
 
-		return nil
+	if err != nil {
+		log.Fatalf("Could not parse process ID: %s (%s)\n", matches[2], err)
 	}
 
-	return &ProcessLogEvent{
-		SyslogTime: syslogTime,
-		Name:       name,
-		ProcessId:  int(processId),
-		Content:    content,
-	}
+	return matches[1], int(processId), matches[3], true
 }
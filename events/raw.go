@@ -0,0 +1,52 @@
+package events
+
+import (
+	"fmt"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+// RawLogEvent is a line no parser recognised, kept as it was read so it can
+// still be looked at (e.g., to write a parser for it). Lines that aren't even
+// syslog lines only have the source's label in their header.
+type RawLogEvent struct {
+	SyslogHeader
+	Line string
+}
+
+func (e *RawLogEvent) PrintLine(index int) {
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, ct.None, false)
+	fmt.Print("unparsed  ")
+	ct.ResetColor()
+	fmt.Printf("%s\n", e.Line)
+}
+
+func (e *RawLogEvent) PrintFull() {
+	fmt.Printf("\n---------- UNPARSED LOG LINE ----------\n")
+	fmt.Printf(
+		"SyslogTime: %s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Printf("Source:     %s\n", e.Source)
+	fmt.Printf("Line:       %s\n", e.Line)
+	fmt.Printf("---------------------------------------\n\n")
+}
+
+func (e *RawLogEvent) Summary() string {
+	return "unparsed"
+}
+
+func (e *RawLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	return false
+}
+
+func NewRawLogEvent(header SyslogHeader, line string) *RawLogEvent {
+	return &RawLogEvent{
+		SyslogHeader: header,
+		Line:         line,
+	}
+}
@@ -0,0 +1,408 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+var redisRegexp = regexp.MustCompile(
+	"^(?P<tag>redis[^\\[: ]*)(?:\\[(?P<pid>[0-9]{1,})\\])?: (?P<content>.*)$",
+)
+var redisPrefixRegexp = regexp.MustCompile(
+	"^(?:(?P<pid>[0-9]{1,}):(?P<role>[MSCX])|\\[(?P<oldPid>[0-9]{1,})\\]) " +
+		"[0-9]{1,2} [A-Za-z]{3} (?:[0-9]{4} )?[0-9:.]+ " +
+		"(?P<level>[.*#-]) (?P<content>.*)$",
+)
+var redisSentinelRegexp = regexp.MustCompile(
+	"^(?P<sign>[+-])(?P<event>[a-z][a-z-]*)(?: (?P<details>.*))?$",
+)
+
+// redisKinds are tried in order by redisKind. They match whole words or the
+// messages Redis logs, as e.g. "sync" is part of "async" and "maxmemory" is
+// in the warning about the limit set on 32 bit instances.
+var redisKinds = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"oom", regexp.MustCompile("(?i)OOM command not allowed|\\bout of memory\\b")},
+	{"persistence", regexp.MustCompile(
+		"(?i)\\b(?:saving|DB saved|can't save|AOF|RDB|append only file)\\b",
+	)},
+	{"replication", regexp.MustCompile(
+		"(?i)\\b(?:master|replicas?|slaves?|p?sync|resync|" +
+			"(?:re)?synchroni[sz]ation|replication)\\b",
+	)},
+	{"lifecycle", regexp.MustCompile(
+		"(?i)server started|ready to accept connections|" +
+			"received SIG(?:TERM|INT)|ready to exit",
+	)},
+}
+
+var redisFailedRegexp = regexp.MustCompile(
+	"(?i)\\b(?:errors?|fail(?:s|ed|ure|ing)?|can't|cannot|unable|MISCONF)\\b",
+)
+
+// RedisLogEvent is a message from a Redis server. Kind groups the messages
+// worth keeping an eye on: persistence (saving and AOF rewrites), oom,
+// replication (including failovers) and lifecycle (starting and stopping).
+type RedisLogEvent struct {
+	SyslogHeader
+	Tag      string
+	Pid      int
+	Role     string
+	LogLevel string
+	Kind     string
+	Failed   bool
+	Content  string
+}
+
+// redisRoles maps the role markers Redis puts after its PID to the roles.
+var redisRoles = map[string]string{
+	"M": "master",
+	"S": "replica",
+	"C": "child",
+	"X": "sentinel",
+}
+
+// redisLogLevels maps the markers Redis puts before a message to its level.
+var redisLogLevels = map[string]string{
+	".": "debug",
+	"-": "verbose",
+	"*": "notice",
+	"#": "warning",
+}
+
+func (e *RedisLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	if e.Failed || e.Kind == "oom" {
+		background = ct.Red
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("redis  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s-%s-%s  ", e.Tag, e.Role, e.LogLevel)
+	ct.ChangeColor(ct.None, false, background, false)
+	fmt.Printf("%s\n", e.Content)
+	ct.ResetColor()
+}
+
+func (e *RedisLogEvent) PrintFull() {
+	fmt.Printf("\n---------- REDIS LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Tag:\t%s\n", e.Tag)
+	fmt.Fprintf(writer, "Pid:\t%d\n", e.Pid)
+	fmt.Fprintf(writer, "Role:\t%s\n", e.Role)
+	fmt.Fprintf(writer, "LogLevel:\t%s\n", e.LogLevel)
+	fmt.Fprintf(writer, "Kind:\t%s\n", e.Kind)
+	fmt.Fprintf(writer, "Failed:\t%t\n", e.Failed)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	writer.Flush()
+
+	fmt.Printf("---------- REDIS LOG EVENT ----------\n\n")
+}
+
+func (e *RedisLogEvent) Summary() string {
+	if e.Kind == "" {
+		return "redis-" + e.LogLevel
+	}
+
+	if e.Failed {
+		return "redis-" + e.Kind + "-failed"
+	}
+
+	return "redis-" + e.Kind
+}
+
+func (e *RedisLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	if e.Failed || e.Kind == "oom" {
+		return false
+	}
+
+	for _, level := range settings_.GetRedisSuppressLogLevels() {
+		if e.LogLevel == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *RedisLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "tag":
+		return e.Tag, true
+	case "level":
+		return e.LogLevel, true
+	case "role":
+		return e.Role, true
+	}
+
+	return "", false
+}
+
+// RedisSentinelLogEvent is an event reported by Redis Sentinel, e.g., +sdown
+// (an instance is subjectively down), +odown (objectively down) or
+// +switch-master (a failover happened). Cleared is set for events that start
+// with "-", which Sentinel logs when the condition no longer holds.
+type RedisSentinelLogEvent struct {
+	SyslogHeader
+	Tag          string
+	Event        string
+	Cleared      bool
+	InstanceType string
+	Name         string
+	Address      string
+	Master       string
+	Details      string
+}
+
+// isAlarming reports whether the event means a master is down or has been
+// replaced.
+func (e *RedisSentinelLogEvent) isAlarming() bool {
+	switch e.Event {
+	case "odown", "switch-master", "failover-end", "try-failover":
+		return !e.Cleared
+	case "sdown":
+		return !e.Cleared && e.InstanceType == "master"
+	}
+
+	return false
+}
+
+func (e *RedisSentinelLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	if e.isAlarming() {
+		background = ct.Red
+	}
+
+	sign := "+"
+
+	if e.Cleared {
+		sign = "-"
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("redis-sentinel  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s%s-%s  ", sign, e.Event, e.Master)
+	ct.ChangeColor(ct.None, false, background, false)
+	fmt.Printf("%s\n", e.Details)
+	ct.ResetColor()
+}
+
+func (e *RedisSentinelLogEvent) PrintFull() {
+	fmt.Printf("\n---------- REDIS SENTINEL LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Tag:\t%s\n", e.Tag)
+	fmt.Fprintf(writer, "Event:\t%s\n", e.Event)
+	fmt.Fprintf(writer, "Cleared:\t%t\n", e.Cleared)
+	fmt.Fprintf(writer, "InstanceType:\t%s\n", e.InstanceType)
+	fmt.Fprintf(writer, "Name:\t%s\n", e.Name)
+	fmt.Fprintf(writer, "Address:\t%s\n", e.Address)
+	fmt.Fprintf(writer, "Master:\t%s\n", e.Master)
+	fmt.Fprintf(writer, "Details:\t%s\n", e.Details)
+
+	writer.Flush()
+
+	fmt.Printf("---------- REDIS SENTINEL LOG EVENT ----------\n\n")
+}
+
+func (e *RedisSentinelLogEvent) Summary() string {
+	if e.Cleared {
+		return "redis-sentinel-" + e.Event + "-cleared"
+	}
+
+	return "redis-sentinel-" + e.Event
+}
+
+func (e *RedisSentinelLogEvent) Suppress(
+	settings_ settings.SettingsInterface,
+) bool {
+	return false
+}
+
+func (e *RedisSentinelLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "master":
+		return e.Master, true
+	case "address":
+		return e.Address, true
+	}
+
+	return "", false
+}
+
+func init() {
+	RegisterParser(Parser{
+		Name:     "redis",
+		Priority: 38,
+		Match:    NewRedisLogEvent,
+	})
+}
+
+// NewRedisLogEvent parses messages from processes tagged redis* (e.g.,
+// redis-server or redis-sentinel). Redis only prefixes messages with its PID,
+// role, time and level when writing to a log file, so the prefix is optional
+// and the syslog severity stands in for the level without it.
+func NewRedisLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	matches := redisRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return nil
+	}
+
+	tag := matches[1]
+	pid, _ := strconv.Atoi(matches[2])
+	content := matches[3]
+	role := ""
+	logLevel := redisSyslogLevel(header.Severity)
+
+	if matches = redisPrefixRegexp.FindStringSubmatch(content); matches != nil {
+		pid, _ = strconv.Atoi(matches[1] + matches[3])
+		role = redisRoles[matches[2]]
+		logLevel = redisLogLevels[matches[4]]
+		content = matches[5]
+	}
+
+	if matches = redisSentinelRegexp.FindStringSubmatch(content); matches != nil {
+		return newRedisSentinelLogEvent(
+			header,
+			tag,
+			matches[1] == "-",
+			matches[2],
+			matches[3],
+		)
+	}
+
+	kind, failed := redisKind(content)
+
+	return &RedisLogEvent{
+		SyslogHeader: header,
+		Tag:          tag,
+		Pid:          pid,
+		Role:         role,
+		LogLevel:     logLevel,
+		Kind:         kind,
+		Failed:       failed,
+		Content:      content,
+	}
+}
+
+// redisSyslogLevel maps the syslog severity of a message to the Redis log
+// level it was logged at. Messages without a priority are taken as notices.
+func redisSyslogLevel(severity int) string {
+	switch SeverityName(severity) {
+	case "debug":
+		return "debug"
+	case "info":
+		return "verbose"
+	case "warning", "err", "crit", "alert", "emerg":
+		return "warning"
+	}
+
+	return "notice"
+}
+
+// redisKind works out what a Redis message is about, and whether it reports
+// a failure.
+func redisKind(content string) (string, bool) {
+	for _, kind := range redisKinds {
+		if !kind.re.MatchString(content) {
+			continue
+		}
+
+		if kind.name == "oom" || kind.name == "lifecycle" {
+			return kind.name, false
+		}
+
+		return kind.name, redisFailedRegexp.MatchString(content)
+	}
+
+	return "", false
+}
+
+// newRedisSentinelLogEvent parses the details of a Sentinel event, which are
+// usually "<type> <name> <ip> <port> @ <master> <ip> <port>", but are
+// "<master> <old ip> <old port> <new ip> <new port>" for +switch-master.
+func newRedisSentinelLogEvent(
+	header SyslogHeader,
+	tag string,
+	cleared bool,
+	event string,
+	details string,
+) *RedisSentinelLogEvent {
+	sentinelEvent := &RedisSentinelLogEvent{
+		SyslogHeader: header,
+		Tag:          tag,
+		Event:        event,
+		Cleared:      cleared,
+		Details:      details,
+	}
+
+	fields := strings.Fields(details)
+
+	if event == "switch-master" && len(fields) >= 5 {
+		sentinelEvent.InstanceType = "master"
+		sentinelEvent.Name = fields[0]
+		sentinelEvent.Master = fields[0]
+		sentinelEvent.Address = fields[3] + ":" + fields[4]
+
+		return sentinelEvent
+	}
+
+	if len(fields) >= 4 {
+		sentinelEvent.InstanceType = fields[0]
+		sentinelEvent.Name = fields[1]
+		sentinelEvent.Address = fields[2] + ":" + fields[3]
+
+		if sentinelEvent.InstanceType == "master" {
+			sentinelEvent.Master = sentinelEvent.Name
+		}
+	}
+
+	for i, field := range fields {
+		if field == "@" && i+1 < len(fields) {
+			sentinelEvent.Master = fields[i+1]
+		}
+	}
+
+	return sentinelEvent
+}
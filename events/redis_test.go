@@ -0,0 +1,139 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestRedisLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message string
+		summary string
+		role    string
+		level   string
+	}{
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 * Background saving started by pid 5678",
+			"redis-persistence", "master", "notice",
+		},
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 # Background saving error",
+			"redis-persistence-failed", "master", "warning",
+		},
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 # Can't save in background: fork: Cannot allocate memory",
+			"redis-persistence-failed", "master", "warning",
+		},
+		{
+			"redis-server[1234]: 1234:S 17 Oct 2026 10:00:00.123 * MASTER <-> REPLICA sync started",
+			"redis-replication", "replica", "notice",
+		},
+		{
+			"redis-server[1234]: 1234:S 17 Oct 2026 10:00:00.123 # Error condition on socket for SYNC: Connection refused",
+			"redis-replication-failed", "replica", "warning",
+		},
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 # WARNING: 32 bit instance detected but no memory limit set. Setting 3 GB maxmemory limit with 'noeviction' policy now.",
+			"redis-warning", "master", "warning",
+		},
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 * Module 'bf' loaded from /usr/lib/redis/modules/redisbloom.so",
+			"redis-notice", "master", "notice",
+		},
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 * Lazy freeing is done in an async thread",
+			"redis-notice", "master", "notice",
+		},
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 # Out Of Memory allocating 1048576 bytes!",
+			"redis-oom", "master", "warning",
+		},
+		{
+			"redis-server[1234]: 1234:M 17 Oct 2026 10:00:00.123 * Ready to accept connections",
+			"redis-lifecycle", "master", "notice",
+		},
+		{
+			"redis-server[1234]: DB saved on disk",
+			"redis-persistence", "", "notice",
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*RedisLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't a redis event", test.message)
+			continue
+		}
+
+		if event.Summary() != test.summary {
+			t.Errorf("%q: Summary() = %q, want %q", test.message, event.Summary(), test.summary)
+		}
+
+		if event.Role != test.role || event.LogLevel != test.level {
+			t.Errorf(
+				"%q: role, level = %q, %q, want %q, %q",
+				test.message,
+				event.Role,
+				event.LogLevel,
+				test.role,
+				test.level,
+			)
+		}
+	}
+}
+
+func TestRedisSentinelLogEvent(t *testing.T) {
+	message := "redis-sentinel[2000]: 2000:X 17 Oct 2026 10:00:00.123 # " +
+		"+switch-master mymaster 10.0.0.1 6379 10.0.0.2 6379"
+
+	event, ok := parseTestLine(t, 0, message).(*RedisSentinelLogEvent)
+
+	if !ok {
+		t.Fatalf("%q isn't a sentinel event", message)
+	}
+
+	if event.Summary() != "redis-sentinel-switch-master" ||
+		event.Master != "mymaster" ||
+		event.Address != "10.0.0.2:6379" {
+		t.Errorf("%q parsed as %+v", message, event)
+	}
+}
+
+func TestResqueLogEvent(t *testing.T) {
+	for _, test := range []struct {
+		message string
+		status  string
+		queue   string
+		class   string
+		error_  string
+	}{
+		{
+			"resque-worker[3000]: [notice] [10:00:00 2026-10-17] Starting work on (Job{emails} | ID: 4f2a | SendEmail | [{\"to\":\"a@example.com\"}])",
+			"started", "emails", "SendEmail", "",
+		},
+		{
+			"resque-worker[3000]: [notice] [10:00:01 2026-10-17] (Job{emails} | ID: 4f2a | SendEmail | [{\"to\":\"a@example.com\"}]) has finished",
+			"finished", "emails", "SendEmail", "",
+		},
+		{
+			"resque-worker[3000]: [critical] [10:00:01 2026-10-17] (Job{emails} | ID: 4f2a | SendEmail | []) has failed Connection refused",
+			"failed", "emails", "SendEmail", "Connection refused",
+		},
+		{
+			"resque[4000]: done: (Job{default} | ImportOrders | [12])",
+			"finished", "default", "ImportOrders", "",
+		},
+	} {
+		event, ok := parseTestLine(t, 0, test.message).(*ResqueLogEvent)
+
+		if !ok {
+			t.Errorf("%q isn't a resque event", test.message)
+			continue
+		}
+
+		if event.Status != test.status ||
+			event.Queue != test.queue ||
+			event.Class != test.class ||
+			event.Error != test.error_ {
+			t.Errorf("%q parsed as %+v", test.message, event)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	ct "github.com/daviddengcn/go-colortext"
+	settings "github.com/lovek323/bclog/settings"
+)
+
+var resqueRegexp = regexp.MustCompile(
+	"^(?P<tag>[^ :\\[]+)(?:\\[(?P<pid>[0-9]{1,})\\])?: " +
+		"(?:\\[[a-z]+\\] )?(?:\\[[0-9:]+ [0-9-]+\\] )?" +
+		"(?P<before>.*?)\\(Job\\{(?P<queue>[^}]*)\\} \\| " +
+		"(?:ID: (?P<id>[^ |]+) \\| )?(?P<class>[^ |]+) \\| " +
+		"(?P<args>.*?)\\)" +
+		"(?P<after>(?: has finished| has failed.*| failed:.*)?)$",
+)
+
+// ResqueLogEvent is a Resque worker starting, finishing or failing a job.
+// Status is "started", "finished" or "failed", and Error is what the job
+// failed with.
+type ResqueLogEvent struct {
+	SyslogHeader
+	Tag     string
+	Pid     int
+	Status  string
+	Queue   string
+	JobId   string
+	Class   string
+	Args    string
+	Error   string
+	Content string
+}
+
+func (e *ResqueLogEvent) PrintLine(index int) {
+	background := ct.None
+
+	if e.Status == "failed" {
+		background = ct.Red
+	}
+
+	fmt.Printf("[%d]  ", index)
+	fmt.Print(e.SyslogTime.Format("2006-01-02 15:04:05") + "  ")
+	fmt.Printf("%s  ", e.Source)
+	ct.ChangeColor(ct.Yellow, false, background, false)
+	fmt.Print("resque  ")
+	ct.ChangeColor(ct.Cyan, false, background, false)
+	fmt.Printf("%s-%s  ", e.Queue, e.Status)
+	ct.ChangeColor(ct.None, false, background, false)
+
+	if e.Error != "" {
+		fmt.Printf("%s: %s\n", e.Class, e.Error)
+	} else {
+		fmt.Printf("%s %s\n", e.Class, e.Args)
+	}
+
+	ct.ResetColor()
+}
+
+func (e *ResqueLogEvent) PrintFull() {
+	fmt.Printf("\n---------- RESQUE LOG EVENT ----------\n")
+
+	writer := new(tabwriter.Writer)
+	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(
+		writer,
+		"SyslogTime:\t%s\n",
+		e.SyslogTime.Format("2006-01-02 15:04:05"),
+	)
+	fmt.Fprintf(writer, "Source:\t%s\n", e.Source)
+	fmt.Fprintf(writer, "Tag:\t%s\n", e.Tag)
+	fmt.Fprintf(writer, "Pid:\t%d\n", e.Pid)
+	fmt.Fprintf(writer, "Status:\t%s\n", e.Status)
+	fmt.Fprintf(writer, "Queue:\t%s\n", e.Queue)
+	fmt.Fprintf(writer, "JobId:\t%s\n", e.JobId)
+	fmt.Fprintf(writer, "Class:\t%s\n", e.Class)
+	fmt.Fprintf(writer, "Args:\t%s\n", e.Args)
+	fmt.Fprintf(writer, "Error:\t%s\n", e.Error)
+	fmt.Fprintf(writer, "Content:\t%s\n", e.Content)
+
+	writer.Flush()
+
+	fmt.Printf("---------- RESQUE LOG EVENT ----------\n\n")
+}
+
+func (e *ResqueLogEvent) Summary() string {
+	return "resque-" + e.Status
+}
+
+func (e *ResqueLogEvent) Suppress(settings_ settings.SettingsInterface) bool {
+	for _, status := range settings_.GetResqueSuppressStatuses() {
+		if e.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *ResqueLogEvent) GetField(name string) (string, bool) {
+	switch name {
+	case "queue":
+		return e.Queue, true
+	case "class":
+		return e.Class, true
+	case "job_id":
+		return e.JobId, true
+	}
+
+	return "", false
+}
+
+func init() {
+	RegisterParser(Parser{
+		Name:     "resque",
+		Priority: 37,
+		Match:    NewResqueLogEvent,
+	})
+}
+
+// NewResqueLogEvent parses the job messages of Resque and php-resque workers,
+// whatever they're tagged with. Jobs are described as
+// "(Job{queue} | class | args)", and php-resque adds "ID: id | " before the
+// class.
+func NewResqueLogEvent(
+	header SyslogHeader,
+	message string,
+) LogEventInterface {
+	matches := resqueRegexp.FindStringSubmatch(message)
+
+	if matches == nil {
+		return nil
+	}
+
+	event := &ResqueLogEvent{
+		SyslogHeader: header,
+		Tag:          matches[1],
+		Queue:        matches[4],
+		JobId:        matches[5],
+		Class:        matches[6],
+		Args:         matches[7],
+		Content:      message[strings.Index(message, ": ")+2:],
+	}
+
+	event.Pid, _ = strconv.Atoi(matches[2])
+
+	before := matches[3]
+	after := matches[8]
+
+	switch {
+	case before == "Starting work on " || before == "got: ":
+		event.Status = "started"
+	case before == "done: " || after == " has finished":
+		event.Status = "finished"
+	case strings.HasPrefix(after, " has failed"):
+		event.Status = "failed"
+		event.Error = strings.TrimSpace(strings.TrimPrefix(after, " has failed"))
+	case strings.HasPrefix(after, " failed:"):
+		event.Status = "failed"
+		event.Error = strings.TrimSpace(strings.TrimPrefix(after, " failed:"))
+	default:
+		// Other messages about a job, e.g., Resque running its hooks, are
+		// left to the other parsers.
+		return nil
+	}
+
+	return event
+}
@@ -1,18 +1,18 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"os/user"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
@@ -21,20 +21,122 @@ import (
 
 	"github.com/lovek323/bclog/events"
 	"github.com/lovek323/bclog/settings"
+	"github.com/lovek323/bclog/sources"
+)
+
+var priorityRegexp = regexp.MustCompile("^<([0-9]{1,3})>")
+var rfc5424Regexp = regexp.MustCompile(
+	"^[0-9]{1,2} (?P<timestamp>[^ ]+) (?P<hostname>[^ ]+) " +
+		"(?P<appName>[^ ]+) (?P<procId>[^ ]+) (?P<msgId>[^ ]+) " +
+		"(?P<rest>.*)$",
+)
+var syslogRegexp = regexp.MustCompile(
+	"^(?P<date>(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)(?:[ ]{1,})" +
+		"(?:[0-9]{1,}) [0-9]{2}:[0-9]{2}:[0-9]{2}|" +
+		"[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(?:\\.[0-9]+)?" +
+		"(?:Z|[+-][0-9]{2}:[0-9]{2})) " +
+		"(?P<source>.*?) " +
+		"(?P<message>.*)$",
+)
+var structuredDataRegexp = regexp.MustCompile(
+	"^\\[(?P<id>[^ \\]=\"]+)(?P<params>(?: [^ \\]=\"]+=\"(?:[^\"\\\\]|\\\\.)*\")*)\\]",
+)
+var structuredDataParamRegexp = regexp.MustCompile(
+	"(?P<name>[^ \\]=\"]+)=\"(?P<value>(?:[^\"\\\\]|\\\\.)*)\"",
+)
+var structuredDataEscapeReplacer = strings.NewReplacer(
+	"\\\"", "\"",
+	"\\\\", "\\",
+	"\\]", "]",
 )
 
 var history []events.LogEventInterface
 var statistics map[string][]events.LogEventInterface
 var settings_ settings.Settings
 var lastPrompt time.Time
+var location *time.Location = time.Local
+
+// clockSpeed is how many times faster than real time the log is being read,
+// which is only ever not 1 when replaying.
+var clockSpeed float64 = 1
+var source sources.SourceInterface
+
+var sourceType = flag.String(
+	"source",
+	"",
+	"where to read the log from: ssh, file, stdin or syslog (overrides config, "+
+		"including Sources)",
+)
+var sourcePath = flag.String(
+	"path",
+	"",
+	"path of the log file to follow (overrides config, including Sources)",
+)
+var archiveFiles fileList
+
+// fileList collects the values of a flag that can be given more than once.
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+
+	return nil
+}
 
 func main() {
+	flag.Var(
+		&archiveFiles,
+		"file",
+		"read a (possibly gzip or bzip2 compressed) log archive instead of "+
+			"following a live log; can be given more than once",
+	)
+	flag.Parse()
+
+	replayFile := ""
+
+	if flag.Arg(0) == "replay" {
+		replayFile, clockSpeed = parseReplayArgs(flag.Args()[1:])
+	}
+
 	statistics = make(map[string][]events.LogEventInterface)
 
 	loadConfig()
 
+	// The flags describe the one source to read, so they replace any
+	// Sources from the config rather than being silently ignored.
+	if *sourceType != "" || *sourcePath != "" {
+		settings_.Sources = nil
+	}
+	if *sourceType != "" {
+		settings_.Source.Type = *sourceType
+	}
+	if *sourcePath != "" {
+		settings_.Source.Path = *sourcePath
+	}
+
+	var err error
+
+	if replayFile != "" {
+		source = sources.NewReplaySource(
+			sources.NewArchiveSource("", []string{replayFile}),
+			clockSpeed,
+		)
+	} else if len(archiveFiles) > 0 {
+		source = sources.NewArchiveSource("", archiveFiles)
+	} else {
+		source, err = sources.NewSource(&settings_)
+	}
+
+	if err != nil {
+		log.Fatalf("Could not create log source: %s\n", err)
+	}
+
 	linenoise.SetCompletionHandler(func(in string) []string {
-		availableCommands := []string{"clear", "help", "reload", "show", "quit", "summary"}
+		availableCommands := []string{"clear", "cron", "help", "mail", "reload", "show", "quit", "summary", "unparsed"}
 		matchedCommands := []string{}
 
 		for _, command := range availableCommands {
@@ -52,68 +154,155 @@ func main() {
 		return matchedCommands
 	})
 
-	go func() {
-		for {
-			lastPrompt = time.Now()
+	// Archives are read in full before the prompt starts, rather than
+	// printing every line in them.
+	if len(archiveFiles) > 0 {
+		readLog(false)
 
-			line, err := linenoise.Line("> ")
+		fmt.Printf(
+			"Loaded %d events from %d file(s)\n\n",
+			len(history),
+			len(archiveFiles),
+		)
 
-			if err != nil {
-				if err == linenoise.KillSignalError {
-					quit()
-				} else {
-					log.Fatalf("Could not read line: %s\n", err)
-				}
-			}
+		prompt()
+	}
 
-			err = linenoise.AddHistory(line)
+	// A replay comes to an end, but the prompt should stay around afterwards.
+	if replayFile != "" {
+		go func() {
+			readLog(true)
+			fmt.Print("\rReplay finished\n" + promptText())
+		}()
 
-			if err != nil {
-				log.Printf("Failed to add %s to history (%s)\n", line, err)
-			}
+		prompt()
+	}
 
-			args := strings.Split(line, " ")
+	// The prompt reads from stdin too, so it can't run alongside a stdin
+	// source.
+	if !readsStdin(&settings_) {
+		go prompt()
+	}
 
-			if len(args) == 0 {
-				continue
-			}
+	readLog(true)
+}
+
+// readsStdin reports whether any of the configured sources reads from stdin.
+func readsStdin(settings_ *settings.Settings) bool {
+	if len(settings_.Sources) == 0 {
+		return settings_.Source.Type == "stdin"
+	}
+
+	for _, sourceSettings := range settings_.Sources {
+		if sourceSettings.Type == "stdin" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseReplayArgs parses the arguments of "bclog replay <file> [-speed 10x]",
+// which may have the flag before or after the file.
+func parseReplayArgs(args []string) (string, float64) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := flags.String(
+		"speed",
+		"1x",
+		"how many times faster than real time to replay the log, e.g., 10x",
+	)
+
+	flags.Parse(args)
+
+	file := flags.Arg(0)
+
+	if file == "" {
+		log.Fatalf("Usage: bclog replay <file> [-speed 10x]\n")
+	}
+
+	flags.Parse(flags.Args()[1:])
 
-			switch args[0] {
-			case "":
-				summary([]string{"last-prompt"})
-				break
-			case "clear":
-				linenoise.Clear()
-				break
-			case "help":
-				help()
-			case "quit":
+	value, err := strconv.ParseFloat(strings.TrimSuffix(*speed, "x"), 64)
+
+	if err != nil || value <= 0 {
+		log.Fatalf("Invalid replay speed: %s\n", *speed)
+	}
+
+	return file, value
+}
+
+func prompt() {
+	for {
+		lastPrompt = time.Now()
+
+		line, err := linenoise.Line(promptText())
+
+		if err != nil {
+			if err == linenoise.KillSignalError {
 				quit()
-				break
-			case "reload":
-				loadConfig()
-				break
-			case "show":
-				show(args[1:])
-				break
-			case "summary":
-				summary(args[1:])
-				break
-
-			default:
-				index, err := strconv.ParseInt(line, 10, 32)
-
-				if err == nil {
-					event := history[index]
-					event.PrintFull()
-				} else {
-					fmt.Printf("Unrecognised command: %s\n\n", line)
-				}
+			} else {
+				log.Fatalf("Could not read line: %s\n", err)
 			}
 		}
-	}()
 
-	readLog()
+		err = linenoise.AddHistory(line)
+
+		if err != nil {
+			log.Printf("Failed to add %s to history (%s)\n", line, err)
+		}
+
+		args := strings.Split(line, " ")
+
+		if len(args) == 0 {
+			continue
+		}
+
+		recordMutex.RLock()
+
+		switch args[0] {
+		case "":
+			summary([]string{"last-prompt"})
+			break
+		case "clear":
+			linenoise.Clear()
+			break
+		case "cron":
+			cron(args[1:])
+			break
+		case "help":
+			help()
+		case "mail":
+			mail(args[1:])
+			break
+		case "quit":
+			quit()
+			break
+		case "reload":
+			loadConfig()
+			break
+		case "show":
+			show(args[1:])
+			break
+		case "summary":
+			summary(args[1:])
+			break
+		case "unparsed":
+			unparsed(args[1:])
+			break
+
+		default:
+			index, err := strconv.ParseInt(line, 10, 32)
+
+			if err == nil {
+				event := history[index]
+				event.PrintFull()
+			} else {
+				fmt.Printf("Unrecognised command: %s\n\n", line)
+			}
+		}
+
+		recordMutex.RUnlock()
+	}
 }
 
 func loadConfig() {
@@ -131,7 +320,48 @@ func loadConfig() {
 	if err != nil {
 		log.Fatalf("Error reading ~/.config/bclog/config.json: %s", err)
 	}
-	fmt.Println("Loaded config\n")
+
+	if err = events.RegisterCustomParsers(settings_.CustomEvents); err != nil {
+		log.Fatalf("Error reading ~/.config/bclog/config.json: %s", err)
+	}
+
+	events.ConfigureParsers(&settings_)
+
+	err = events.ConfigureNginxErrorSuppression(
+		settings_.NginxError.SuppressContentRegexes,
+	)
+
+	if err != nil {
+		log.Fatalf("Error reading ~/.config/bclog/config.json: %s", err)
+	}
+
+	logFormat := settings_.NginxAccess.LogFormat
+
+	if logFormat == "" {
+		logFormat = events.DefaultNginxLogFormat
+	}
+
+	nginxLogFormat, err := events.NewNginxLogFormat(logFormat)
+
+	if err != nil {
+		log.Fatalf("Invalid nginx log format in ~/.config/bclog/config.json: %s", err)
+	}
+
+	events.RegisterNginxParser(nginxLogFormat, settings_.NginxAccess.SlowRequestTime)
+
+	timezone := settings_.Timezone
+
+	// LoadLocation takes an empty name to mean UTC rather than local time.
+	if timezone == "" {
+		timezone = "Local"
+	}
+
+	location, err = time.LoadLocation(timezone)
+
+	if err != nil {
+		log.Fatalf("Invalid timezone in ~/.config/bclog/config.json: %s", err)
+	}
+	fmt.Print("Loaded config\n\n")
 }
 
 func quit() {
@@ -142,9 +372,11 @@ func summary(args []string) {
 	var duration time.Duration
 	var err error
 
-	if len(args) > 0 {
+	if len(args) > 0 && !strings.Contains(args[0], "=") {
 		if args[0] == "last-prompt" {
-			duration = time.Now().Sub(lastPrompt)
+			duration = time.Duration(
+				float64(time.Now().Sub(lastPrompt)) * clockSpeed,
+			)
 		} else {
 			duration, err = time.ParseDuration(args[0])
 
@@ -153,13 +385,17 @@ func summary(args []string) {
 					"Invalid syntax: first argument to summary must be a valid " +
 						"duration or empty",
 				)
-				fmt.Println("summary [duration]\n")
+				fmt.Print("summary [duration] [field=value ...] [by=field]\n\n")
 			}
 		}
+
+		args = args[1:]
 	} else {
 		duration, _ = time.ParseDuration("24h")
 	}
 
+	filters, groupBy := parseFilters(args)
+
 	ct.ChangeColor(ct.Yellow, true, ct.None, false)
 	fmt.Printf("\nSUMMARY (LAST %s)\n", duration)
 	ct.ResetColor()
@@ -167,33 +403,42 @@ func summary(args []string) {
 	writer := new(tabwriter.Writer)
 	writer.Init(os.Stdout, 0, 8, 2, ' ', 0)
 
-	for summary, events := range statistics {
-		lastDuration := history[len(history)-1].GetSyslogTime().Sub(
-			events[len(events)-1].GetSyslogTime(),
-		)
-
-		count := 0
+	for summary, summaryEvents := range statistics {
+		counts := make(map[string]int)
+		lastEvents := make(map[string]events.LogEventInterface)
 
-		for _, event := range events {
-			if event == nil {
+		for _, event := range summaryEvents {
+			if event == nil || !matchesFilters(event, filters) {
 				continue
 			}
+
+			group := summary
+
+			if groupBy != "" {
+				value, _ := eventField(event, groupBy)
+				group += "\t" + value
+			}
+
+			lastEvents[group] = event
+
 			if history[len(history)-1].GetSyslogTime().Sub(event.GetSyslogTime()) <= duration {
-				count++
+				counts[group]++
 			}
 		}
 
-		if count == 0 {
-			continue
+		for group, count := range counts {
+			lastDuration := history[len(history)-1].GetSyslogTime().Sub(
+				lastEvents[group].GetSyslogTime(),
+			)
+
+			fmt.Fprintf(
+				writer,
+				"%s\t%d event(s)\tLast %s ago\n",
+				group,
+				count,
+				lastDuration,
+			)
 		}
-
-		fmt.Fprintf(
-			writer,
-			"%s\t%d event(s)\tLast %s ago\n",
-			summary,
-			count,
-			lastDuration,
-		)
 	}
 
 	writer.Flush()
@@ -204,7 +449,7 @@ func summary(args []string) {
 func show(args []string) {
 	if len(args) < 2 {
 		fmt.Println("Invalid syntax: show requires two arguments")
-		fmt.Println("show <type> <duration>\n")
+		fmt.Print("show <type> <duration> [field=value ...]\n\n")
 
 		return
 	}
@@ -216,19 +461,241 @@ func show(args []string) {
 		fmt.Println(
 			"Invalid syntax: second argument to show must be a valid duration",
 		)
-		fmt.Println("show <type> <duration>\n")
+		fmt.Print("show <type> <duration> [field=value ...]\n\n")
 	}
 
+	filters, _ := parseFilters(args[2:])
+
 	fmt.Println("\n---------- SHOW ----------")
 	fmt.Printf("Showing %s events from the last %s\n", summary, duration)
 
 	for index, event := range history {
 		if (summary == "*" || event.Summary() == summary) &&
+			matchesFilters(event, filters) &&
 			history[len(history)-1].GetSyslogTime().Sub(event.GetSyslogTime()) <= duration {
 			event.PrintLine(index)
 		}
 	}
-	fmt.Println("--------------------------\n")
+	fmt.Print("--------------------------\n\n")
+}
+
+// cronRecentRuns is how many runs of each command the cron command lists.
+const cronRecentRuns = 5
+
+// cron lists the most recent runs of each cron job over the last duration,
+// with the index of the line that started each run. A run is listed if the
+// line that started it matches the filters.
+func cron(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Invalid syntax: cron requires a duration")
+		fmt.Print("cron <duration> [field=value ...]\n\n")
+
+		return
+	}
+
+	duration, err := time.ParseDuration(args[0])
+
+	if err != nil {
+		fmt.Println(
+			"Invalid syntax: first argument to cron must be a valid duration",
+		)
+		fmt.Print("cron <duration> [field=value ...]\n\n")
+
+		return
+	}
+
+	filters, _ := parseFilters(args[1:])
+
+	runs := make(map[string][]int)
+	commands := []string{}
+
+	for index, event := range history {
+		cronEvent, ok := event.(*events.CronLogEvent)
+
+		if !ok || cronEvent.Run == nil || cronEvent.Kind != "cmd" ||
+			history[len(history)-1].GetSyslogTime().Sub(event.GetSyslogTime()) > duration ||
+			!matchesFilters(event, filters) {
+			continue
+		}
+
+		if _, exists := runs[cronEvent.Run.Command]; !exists {
+			commands = append(commands, cronEvent.Run.Command)
+		}
+
+		runs[cronEvent.Run.Command] = append(runs[cronEvent.Run.Command], index)
+	}
+
+	fmt.Println("\n---------- CRON ----------")
+	fmt.Printf("Showing cron jobs run in the last %s\n", duration)
+
+	for _, command := range commands {
+		indexes := runs[command]
+
+		ct.ChangeColor(ct.Yellow, false, ct.None, false)
+		fmt.Printf("\n%s", command)
+		ct.ResetColor()
+		fmt.Printf("  (%d run(s))\n", len(indexes))
+
+		if len(indexes) > cronRecentRuns {
+			indexes = indexes[len(indexes)-cronRecentRuns:]
+		}
+
+		for _, index := range indexes {
+			history[index].(*events.CronLogEvent).PrintRunLine(index)
+		}
+	}
+
+	fmt.Print("--------------------------\n\n")
+}
+
+// mail lists the messages postfix handled over the last duration, one line
+// per message, with the index of the latest line logged about it. A message is
+// listed if any of its lines match the filters.
+func mail(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Invalid syntax: mail requires a duration")
+		fmt.Print("mail <duration> [field=value ...]\n\n")
+
+		return
+	}
+
+	duration, err := time.ParseDuration(args[0])
+
+	if err != nil {
+		fmt.Println(
+			"Invalid syntax: first argument to mail must be a valid duration",
+		)
+		fmt.Print("mail <duration> [field=value ...]\n\n")
+
+		return
+	}
+
+	filters, _ := parseFilters(args[1:])
+
+	latest := make(map[*events.PostfixMessage]int)
+	matched := make(map[*events.PostfixMessage]bool)
+	messages := []*events.PostfixMessage{}
+
+	for index, event := range history {
+		postfixEvent, ok := event.(*events.PostfixLogEvent)
+
+		if !ok || postfixEvent.Message == nil ||
+			history[len(history)-1].GetSyslogTime().Sub(event.GetSyslogTime()) > duration {
+			continue
+		}
+
+		if _, exists := latest[postfixEvent.Message]; !exists {
+			messages = append(messages, postfixEvent.Message)
+		}
+
+		latest[postfixEvent.Message] = index
+
+		if matchesFilters(event, filters) {
+			matched[postfixEvent.Message] = true
+		}
+	}
+
+	fmt.Println("\n---------- MAIL ----------")
+	fmt.Printf("Showing messages from the last %s\n", duration)
+
+	for _, message := range messages {
+		if matched[message] {
+			index := latest[message]
+			history[index].(*events.PostfixLogEvent).PrintMessageLine(index)
+		}
+	}
+
+	fmt.Print("--------------------------\n\n")
+}
+
+// unparsed prints the lines no parser recognised over the last duration
+// exactly as they were read, e.g., to copy them into a test for a new parser.
+func unparsed(args []string) {
+	duration, _ := time.ParseDuration("24h")
+
+	if len(args) > 0 {
+		var err error
+
+		duration, err = time.ParseDuration(args[0])
+
+		if err != nil {
+			fmt.Println(
+				"Invalid syntax: first argument to unparsed must be a valid " +
+					"duration or empty",
+			)
+			fmt.Print("unparsed [duration]\n\n")
+
+			return
+		}
+	}
+
+	fmt.Println("\n---------- UNPARSED ----------")
+	fmt.Printf("Showing lines that couldn't be parsed from the last %s\n", duration)
+
+	for index, event := range history {
+		raw, ok := event.(*events.RawLogEvent)
+
+		if ok && history[len(history)-1].GetSyslogTime().Sub(event.GetSyslogTime()) <= duration {
+			fmt.Printf("[%d]  %s\n", index, raw.Line)
+		}
+	}
+
+	fmt.Print("------------------------------\n\n")
+}
+
+// parseFilters splits the trailing arguments of summary and show into
+// field=value filters and the field named by a by=field argument, if any.
+func parseFilters(args []string) (map[string]string, string) {
+	filters := make(map[string]string)
+	groupBy := ""
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+
+		if len(parts) != 2 {
+			fmt.Printf("Ignoring invalid filter: %s\n", arg)
+			continue
+		}
+
+		if parts[0] == "by" {
+			groupBy = parts[1]
+		} else {
+			filters[parts[0]] = parts[1]
+		}
+	}
+
+	return filters, groupBy
+}
+
+func matchesFilters(
+	event events.LogEventInterface,
+	filters map[string]string,
+) bool {
+	for field, value := range filters {
+		if actual, ok := eventField(event, field); !ok || actual != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// eventField looks up a field of an event by the name used in filters.
+func eventField(event events.LogEventInterface, field string) (string, bool) {
+	switch field {
+	case "source":
+		return event.GetSource(), true
+	case "facility":
+		return events.FacilityName(event.GetFacility()), true
+	case "severity":
+		return events.SeverityName(event.GetSeverity()), true
+	}
+
+	if fieldEvent, ok := event.(events.FieldInterface); ok {
+		return fieldEvent.GetField(field)
+	}
+
+	return "", false
 }
 
 func help() {
@@ -236,56 +703,112 @@ func help() {
 	fmt.Println("")
 	fmt.Println("clear")
 	fmt.Println("    Clears the screen")
+	fmt.Println("cron <duration> [field=value ...]")
+	fmt.Println("    Lists the most recent runs of each cron job over <duration> duration")
+	fmt.Println("    <duration>")
+	fmt.Println("        Any duration that can be parsed by go's time.ParseDuration() function (see http://golang.org/pkg/time/#ParseDuration)")
+	fmt.Println("    [field=value ...] (optional)")
+	fmt.Println("        Only lists runs whose CMD line's field has the given value, e.g., user=www-data")
 	fmt.Println("help")
 	fmt.Println("    Shows this help text")
+	fmt.Println("mail <duration> [field=value ...]")
+	fmt.Println("    Lists the messages postfix handled over <duration> duration and what became of each recipient")
+	fmt.Println("    <duration>")
+	fmt.Println("        Any duration that can be parsed by go's time.ParseDuration() function (see http://golang.org/pkg/time/#ParseDuration)")
+	fmt.Println("    [field=value ...] (optional)")
+	fmt.Println("        Only lists messages with a line whose field has the given value, e.g., to=someone@example.com")
 	fmt.Println("quit")
 	fmt.Println("    Quits the programme")
 	fmt.Println("reload")
 	fmt.Println("    Reloads your config file (updates any ignores, etc.)")
-	fmt.Println("show <type> <duration>")
+	fmt.Println("show <type> <duration> [field=value ...]")
 	fmt.Println("    Shows events of type <type> over <duration> duration")
 	fmt.Println("    <type>")
 	fmt.Println("        A string, valid types can be listed by using the summary command")
 	fmt.Println("    <duration>")
 	fmt.Println("        Any duration that can be parsed by go's time.ParseDuration() function (see http://golang.org/pkg/time/#ParseDuration)")
-	fmt.Println("summary [duration]")
+	fmt.Println("    [field=value ...] (optional)")
+	fmt.Println("        Only shows events whose field has the given value, e.g., source=app")
+	fmt.Println("summary [duration] [field=value ...] [by=field]")
 	fmt.Println("    Shows a summary of events grouped by type over [duration] duration")
 	fmt.Println("    [duration] (optional, defaults to 24 hours)")
 	fmt.Println("        Any duration that can be parsed by go's time.ParseDuration() function (see http://golang.org/pkg/time/#ParseDuration)")
+	fmt.Println("    [field=value ...] (optional)")
+	fmt.Println("        Only counts events whose field has the given value, e.g., source=app")
+	fmt.Println("    [by=field] (optional)")
+	fmt.Println("        Also groups events by the value of field, e.g., by=source")
+	fmt.Println("unparsed [duration]")
+	fmt.Println("    Lists the lines that couldn't be parsed over [duration] duration as they were read")
+	fmt.Println("    [duration] (optional, defaults to 24 hours)")
+	fmt.Println("        Any duration that can be parsed by go's time.ParseDuration() function (see http://golang.org/pkg/time/#ParseDuration)")
 	fmt.Println("")
-	fmt.Println("Entering a blank command (e.g., pressing <ENTER> at an empty prompt) will show a summary of events since you last requested a summary.");
+	fmt.Println("Entering a blank command (e.g., pressing <ENTER> at an empty prompt) will show a summary of events since you last requested a summary.")
 }
 
-func readLog() {
-	command := exec.Command(
-		"ssh",
-		"vagrant@localhost",
-		"-p2222",
-		"-i",
-		settings_.PrimaryKeyFile,
-		"--",
-		"sudo tail -n "+strconv.FormatInt(int64(settings_.InitialLines), 10)+" -f /var/log/syslog",
-	)
+// promptText returns the prompt, prefixed with the state of the log source
+// whenever it isn't connected.
+func promptText() string {
+	if stateSource, ok := source.(sources.StateInterface); ok {
+		if state := stateSource.State(); state != "" {
+			return "[" + state + "] > "
+		}
+	}
 
-	stdout, err := command.StdoutPipe()
+	return "> "
+}
 
-	if err != nil {
-		log.Printf("Could not create log tail command: %s\n", err)
-	}
+// phpTraceWindow is how long a PHP error waits for more of its stack trace.
+const phpTraceWindow = 2 * time.Second
+
+// recordMutex guards the history and statistics. Events are recorded both as
+// lines are read and when held back events expire, while the commands at the
+// prompt read them.
+var recordMutex sync.RWMutex
 
-	if err = command.Start(); err != nil {
-		log.Printf("Could not start log tail command: %s\n", err)
+// readLog adds every event read from the log source to the history until the
+// source runs out of lines, printing the ones that aren't suppressed if
+// printEvents is set.
+func readLog(printEvents bool) {
+	if err := source.Open(); err != nil {
+		log.Fatalf("Could not open log source: %s\n", err)
 	}
 
-	reader := bufio.NewReader(stdout)
+	defer source.Close()
+
+	assembler := events.NewAssembler(phpTraceWindow)
+	lastTime := time.Now().In(location)
+	done := make(chan bool)
+	stopped := make(chan bool)
+
+	// Nothing is recorded after readLog returns.
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				recordEvents(assembler.Expire(now), printEvents)
+			}
+		}
+	}()
 
 	for {
-		line, err := reader.ReadString('\n')
+		line, err := source.ReadLine()
 
 		if err != nil {
 			if err != io.EOF {
-				log.Printf("Could not read from log tail command stdout: %s\n", err)
-			} else {
+				log.Printf("Could not read from log source: %s\n", err)
+			} else if printEvents {
 				log.Printf("EOF\n")
 			}
 			break
@@ -293,87 +816,205 @@ func readLog() {
 
 		event := getEvent(line)
 
-		if event == nil {
-			log.Printf("\rCould not parse: %s", line)
-			fmt.Print("\r> ")
-		} else {
-			if !event.Suppress(&settings_) {
-				fmt.Print("\r")
-				event.PrintLine(len(history))
-				fmt.Print("\r> ")
-			}
+		// Lines without a timestamp are taken to have been logged with the
+		// line before them.
+		if raw, ok := event.(*events.RawLogEvent); ok && raw.SyslogTime.IsZero() {
+			raw.SyslogTime = lastTime
+		}
 
-			history = append(history, event)
-			summary := event.Summary()
+		lastTime = event.GetSyslogTime()
 
-			if _, exists := statistics[summary]; !exists {
-				statistics[summary] = make([]events.LogEventInterface, 1)
-			}
+		recordEvents(assembler.Add(event), printEvents)
+	}
+
+	recordEvents(assembler.Flush(), printEvents)
+}
 
-			statistics[summary] = append(statistics[summary], event)
+// recordEvents adds events to the history and statistics, printing them if
+// printEvents is set.
+func recordEvents(events_ []events.LogEventInterface, printEvents bool) {
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+
+	for _, event := range events_ {
+		if printEvents && !event.Suppress(&settings_) {
+			fmt.Print("\r")
+			event.PrintLine(len(history))
+			fmt.Print("\r" + promptText())
+		}
+
+		history = append(history, event)
+		summary := event.Summary()
+
+		if _, exists := statistics[summary]; !exists {
+			statistics[summary] = make([]events.LogEventInterface, 1)
 		}
-	}
 
-	command.Wait()
+		statistics[summary] = append(statistics[summary], event)
+	}
 }
 
-func getEvent(text string) events.LogEventInterface {
-	re := regexp.MustCompile(
-		"^(?P<date>(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Oct|Nov|Dec)(?:[ ]{1,})" +
-			"(?:[0-9]{1,}) [0-9]{2}:[0-9]{2}:[0-9]{2}) " +
-			"(?P<source>.*?) " +
-			"(?P<message>.*)\n$",
-	)
+// getEvent parses a line read from the log source. Lines that can't be
+// parsed are kept as they are in a RawLogEvent.
+func getEvent(line sources.Line) events.LogEventInterface {
+	header, message, ok := parseSyslogLine(line.Text)
 
-	matches := re.FindStringSubmatch(text)
+	if !ok {
+		header = events.SyslogHeader{Source: line.Label, Facility: -1, Severity: -1}
 
-	if matches == nil {
-		return nil
+		return events.NewRawLogEvent(header, line.Text)
 	}
 
-	syslogTime, err := time.Parse("Jan 2 15:04:05", matches[1])
+	if line.Label != "" {
+		header.Source = line.Label
+	}
 
-	if err != nil {
-		log.Fatalf("Failed to parse %s (%s)", matches[1], err)
+	event := events.ParseEvent(header, message)
+
+	if event == nil {
+		return events.NewRawLogEvent(header, line.Text)
+	}
+
+	return event
+}
+
+// parseSyslogLine splits a syslog line into its header and message. Lines can
+// start with an optional <priority>, followed by either a classic
+// "Jan  2 15:04:05" or RFC 3339 timestamp and the hostname, or be full
+// RFC 5424 messages. The message of an RFC 5424 line is rewritten to the
+// classic "tag[pid]: message" form so that the event parsers needn't care.
+func parseSyslogLine(text string) (events.SyslogHeader, string, bool) {
+	header := events.SyslogHeader{Facility: -1, Severity: -1}
+
+	if matches := priorityRegexp.FindStringSubmatch(text); matches != nil {
+		priority, _ := strconv.ParseInt(matches[1], 10, 32)
+		header.Facility = int(priority / 8)
+		header.Severity = int(priority % 8)
+		text = text[len(matches[0]):]
 	}
 
-	source := matches[2]
-	message := matches[3]
+	if header.Facility >= 0 {
+		if matches := rfc5424Regexp.FindStringSubmatch(text); matches != nil {
+			syslogTime, err := time.Parse(time.RFC3339Nano, matches[1])
+
+			if matches[1] == "-" {
+				syslogTime = time.Now()
+			} else if err != nil {
+				return header, "", false
+			}
+
+			syslogTime = syslogTime.In(location)
+
+			structuredData, message := parseStructuredData(matches[6])
+
+			header.SyslogTime = syslogTime
+			header.Source = matches[2]
+			header.StructuredData = structuredData
 
-	var event events.LogEventInterface
+			tag := matches[3]
 
-	event = events.NewNginxLogEvent(syslogTime, source, message)
+			if matches[4] != "-" {
+				tag += "[" + matches[4] + "]"
+			}
 
-	if event != (events.LogEventInterface)(nil) {
-		return event
+			return header, tag + ": " + message, true
+		}
 	}
 
-	event = events.NewProcessLogEvent(syslogTime, source, message)
+	matches := syslogRegexp.FindStringSubmatch(text)
 
-	if event != (events.LogEventInterface)(nil) {
-		return event
+	if matches == nil {
+		return header, "", false
 	}
 
-	event = events.NewPhpLogEvent(syslogTime, source, message)
+	syslogTime, err := time.Parse(time.RFC3339Nano, matches[1])
 
-	if phpStackTraceLogEvent, ok := event.(*events.PhpStackTraceLogEvent); ok {
-		for i := len(history) - 1; i >= 0; i-- {
-			if phpLogEvent, ok := (history[i]).(*events.PhpLogEvent); ok {
-				phpLogEvent.AddStackTraceEvent(phpStackTraceLogEvent)
-				break
-			}
+	if err == nil {
+		syslogTime = syslogTime.In(location)
+	} else {
+		syslogTime, err = time.ParseInLocation(
+			"Jan 2 15:04:05",
+			matches[1],
+			location,
+		)
+
+		// An impossible date, e.g., "Jan 32", means the line isn't a syslog
+		// line after all.
+		if err != nil {
+			return header, "", false
+		}
+
+		syslogTime = inferYear(syslogTime, time.Now())
+	}
+
+	header.SyslogTime = syslogTime
+	header.Source = matches[2]
+
+	return header, matches[3], true
+}
+
+// inferYear moves a timestamp that was logged without a year into the most
+// recent year that doesn't put it in the future, so that a line logged on
+// 31 December and read on 1 January lands in the previous year. A day's grace
+// allows for clocks that are a little out, including ones that are already in
+// the next year.
+func inferYear(syslogTime time.Time, now time.Time) time.Time {
+	year := now.In(syslogTime.Location()).Year() + 1
+
+	for {
+		inferred := time.Date(
+			year,
+			syslogTime.Month(),
+			syslogTime.Day(),
+			syslogTime.Hour(),
+			syslogTime.Minute(),
+			syslogTime.Second(),
+			syslogTime.Nanosecond(),
+			syslogTime.Location(),
+		)
+
+		// 29 February only exists in leap years.
+		if inferred.Day() == syslogTime.Day() &&
+			!inferred.After(now.Add(24*time.Hour)) {
+			return inferred
 		}
+
+		year--
 	}
+}
+
+// parseStructuredData parses the structured data elements at the start of
+// the rest of an RFC 5424 message, returning them along with the message
+// that follows.
+func parseStructuredData(
+	rest string,
+) (map[string]map[string]string, string) {
+	structuredData := make(map[string]map[string]string)
 
-	if event != (events.LogEventInterface)(nil) {
-		return event
+	if strings.HasPrefix(rest, "-") {
+		return structuredData, trimMessage(rest[1:])
 	}
 
-	event = events.NewGenericLogEvent(syslogTime, source, message)
+	for {
+		matches := structuredDataRegexp.FindStringSubmatch(rest)
+
+		if matches == nil {
+			return structuredData, trimMessage(rest)
+		}
+
+		params := make(map[string]string)
+
+		for _, param := range structuredDataParamRegexp.FindAllStringSubmatch(matches[2], -1) {
+			params[param[1]] = structuredDataEscapeReplacer.Replace(param[2])
+		}
 
-	if event != (events.LogEventInterface)(nil) {
-		return event
+		structuredData[matches[1]] = params
+		rest = rest[len(matches[0]):]
 	}
+}
 
-	return nil
+// trimMessage strips the space and optional byte order mark that come before
+// the message of an RFC 5424 line.
+func trimMessage(message string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(message, " "), "\ufeff")
 }
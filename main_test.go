@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lovek323/bclog/events"
+	"github.com/lovek323/bclog/sources"
+)
+
+func TestParseSyslogLine(t *testing.T) {
+	location = time.UTC
+
+	for _, test := range []struct {
+		text    string
+		ok      bool
+		source  string
+		message string
+	}{
+		{"Oct 17 10:00:00 web1 php: PHP Warning:  x", true, "web1", "php: PHP Warning:  x"},
+		{"Oct  7 10:00:00 web1 php: x", true, "web1", "php: x"},
+		{"<13>2026-10-17T10:00:00Z web1 app: x", true, "web1", "app: x"},
+		{"<13>1 2026-10-17T10:00:00Z web1 app 123 - - x", true, "web1", "app[123]: x"},
+		{"Jan 32 10:00:00 web1 php: x", false, "", ""},
+		{"Feb 30 25:61:00 web1 php: x", false, "", ""},
+		{"2026-02-30T10:00:00Z web1 php: x", false, "", ""},
+		{"not a syslog line", false, "", ""},
+	} {
+		header, message, ok := parseSyslogLine(test.text)
+
+		if ok != test.ok || ok && (header.Source != test.source || message != test.message) {
+			t.Errorf(
+				"parseSyslogLine(%q) = %q, %q, %t, want %q, %q, %t",
+				test.text,
+				header.Source,
+				message,
+				ok,
+				test.source,
+				test.message,
+				test.ok,
+			)
+		}
+	}
+}
+
+// TestGetEventUnparsed checks that lines that can't be parsed are kept as
+// they were read.
+func TestGetEventUnparsed(t *testing.T) {
+	location = time.UTC
+
+	for _, text := range []string{
+		"Jan 32 10:00:00 web1 php: x",
+		"2026-02-30T10:00:00Z web1 php: x",
+		"#012 continued from the line before",
+	} {
+		event := getEvent(sources.Line{Label: "web", Text: text})
+		raw, ok := event.(*events.RawLogEvent)
+
+		if !ok || raw.Line != text || raw.Source != "web" {
+			t.Errorf("getEvent(%q) = %+v, want it kept raw", text, event)
+		}
+	}
+}
+
+func TestParseReplayArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"-speed", "10x", "capture.log"},
+		{"capture.log", "-speed", "10x"},
+		{"-speed=10", "capture.log"},
+	} {
+		if file, speed := parseReplayArgs(args); file != "capture.log" || speed != 10 {
+			t.Errorf("parseReplayArgs(%q) = %q, %g, want capture.log, 10", args, file, speed)
+		}
+	}
+
+	if _, speed := parseReplayArgs([]string{"capture.log"}); speed != 1 {
+		t.Errorf("default speed = %g, want 1", speed)
+	}
+}
+
+func TestInferYear(t *testing.T) {
+	for _, test := range []struct {
+		logged string
+		now    string
+		want   string
+	}{
+		{"Oct 17 10:00:00", "2026-10-17T10:00:05Z", "2026-10-17T10:00:00Z"},
+		// Logged in December, read in January.
+		{"Dec 31 23:59:58", "2027-01-01T00:00:03Z", "2026-12-31T23:59:58Z"},
+		// A few seconds in the future because of clock skew.
+		{"Oct 17 10:00:05", "2026-10-17T10:00:00Z", "2026-10-17T10:00:05Z"},
+		{"Jan  1 00:00:02", "2026-12-31T23:59:59Z", "2027-01-01T00:00:02Z"},
+		// More than a day in the future means last year.
+		{"Oct 19 10:00:00", "2026-10-17T10:00:00Z", "2025-10-19T10:00:00Z"},
+		// 29 February goes back to the last leap year.
+		{"Feb 29 10:00:00", "2027-03-01T10:00:00Z", "2024-02-29T10:00:00Z"},
+	} {
+		logged, err := time.Parse(time.Stamp, test.logged)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now, _ := time.Parse(time.RFC3339, test.now)
+		want, _ := time.Parse(time.RFC3339, test.want)
+
+		if inferred := inferYear(logged, now); !inferred.Equal(want) {
+			t.Errorf("inferYear(%q, %s) = %s, want %s", test.logged, test.now, inferred, want)
+		}
+	}
+}
@@ -5,14 +5,39 @@ type Settings struct {
 
 	InitialLines int
 
+	// Timezone is the IANA name of the time zone the logged machines use,
+	// e.g., "Australia/Sydney". Defaults to the local time zone.
+	Timezone string
+
+	Source  SourceSettings
+	Sources []SourceSettings
+
+	Parsers struct {
+		Disabled   []string
+		Priorities map[string]int
+	}
+
 	BigcommerceApp struct {
 		SuppressLogLevels []string
 	}
 
 	NginxAccess struct {
+		// LogFormat is the nginx log_format directive the access log is
+		// written with, e.g., "$remote_addr [$time_local] \"$request\" $status".
+		LogFormat string
+
+		// Requests that take at least SlowRequestTime seconds are
+		// highlighted and never suppressed.
+		SlowRequestTime float64
+
 		SuppressStatusCodes []int
 	}
 
+	NginxError struct {
+		SuppressLogLevels      []string
+		SuppressContentRegexes []string
+	}
+
 	Process struct {
 		SuppressNames []string
 	}
@@ -22,18 +47,111 @@ type Settings struct {
 		SuppressContentRegexes []string
 	}
 
+	Mysql struct {
+		SuppressLogLevels []string
+	}
+
+	PhpFpm struct {
+		SuppressLogLevels []string
+	}
+
+	Redis struct {
+		SuppressLogLevels []string
+	}
+
+	Postfix struct {
+		// SuppressStatuses hides delivery attempts by their status, e.g.,
+		// "sent", and SuppressProcesses hides the other lines by the daemon
+		// that logged them, e.g., "qmgr". Warnings and errors are always
+		// shown.
+		SuppressStatuses  []string
+		SuppressProcesses []string
+	}
+
+	Cron struct {
+		// SuppressKinds hides lines by their keyword in lower case, e.g.,
+		// "cmd", or "session" for the PAM session lines. Errors are always
+		// shown.
+		SuppressKinds []string
+	}
+
+	Kernel struct {
+		// SuppressKinds hides messages by what they're about, i.e., "oom",
+		// "oom-kill", "segfault", "disk-error" or "other".
+		SuppressKinds []string
+	}
+
+	Resque struct {
+		// SuppressStatuses hides jobs that are "started", "finished" or
+		// "failed".
+		SuppressStatuses []string
+	}
+
 	Generic struct {
 		SuppressNames []string
 	}
+
+	CustomEvents []CustomEventSettings
+}
+
+type SourceSettings struct {
+	Label string
+	Type  string
+	Path  string
+
+	// Listen is the address syslog sources accept messages on.
+	Listen string
+
+	// The remaining settings only apply to ssh sources.
+	Host           string
+	Port           int
+	User           string
+	KnownHostsFile string
+	HostKeyPolicy  string
+	UseAgent       bool
+}
+
+// CustomEventSettings defines an event type of its own for messages matching
+// Regex. Template is a text/template that is given the named groups of Regex
+// and produces the description shown for each event.
+type CustomEventSettings struct {
+	Name              string
+	Regex             string
+	LevelGroup        string
+	Fields            []string
+	Template          string
+	Priority          int
+	SuppressLogLevels []string
 }
 
 type SettingsInterface interface {
+	GetDisabledParsers() []string
+	GetParserPriorities() map[string]int
 	GetBigcommerceAppSuppressLogLevels() []string
 	GetNginxSuppressStatusCodes() []int
+	GetNginxErrorSuppressLogLevels() []string
+	GetNginxErrorSuppressContentRegexes() []string
 	GetPhpSuppressStackTraces() bool
 	GetPhpSuppressContentRegexes() []string
+	GetMysqlSuppressLogLevels() []string
+	GetPhpFpmSuppressLogLevels() []string
+	GetRedisSuppressLogLevels() []string
+	GetResqueSuppressStatuses() []string
+	GetPostfixSuppressStatuses() []string
+	GetPostfixSuppressProcesses() []string
+	GetCronSuppressKinds() []string
+	GetKernelSuppressKinds() []string
 	GetProcessSuppressNames() []string
 	GetGenericSuppressNames() []string
+	GetCustomEventSuppressLogLevels(name string) []string
+}
+
+func (s *Settings) GetDisabledParsers() []string {
+	return s.Parsers.Disabled
+}
+
+func (s *Settings) GetParserPriorities() map[string]int {
+	return s.Parsers.Priorities
 }
 
 func (s *Settings) GetBigcommerceAppSuppressLogLevels() []string {
@@ -44,6 +162,14 @@ func (s *Settings) GetNginxSuppressStatusCodes() []int {
 	return s.NginxAccess.SuppressStatusCodes
 }
 
+func (s *Settings) GetNginxErrorSuppressLogLevels() []string {
+	return s.NginxError.SuppressLogLevels
+}
+
+func (s *Settings) GetNginxErrorSuppressContentRegexes() []string {
+	return s.NginxError.SuppressContentRegexes
+}
+
 func (s *Settings) GetPhpSuppressStackTraces() bool {
 	return s.Php.SuppressStackTraces
 }
@@ -52,6 +178,38 @@ func (s *Settings) GetPhpSuppressContentRegexes() []string {
 	return s.Php.SuppressContentRegexes
 }
 
+func (s *Settings) GetMysqlSuppressLogLevels() []string {
+	return s.Mysql.SuppressLogLevels
+}
+
+func (s *Settings) GetPhpFpmSuppressLogLevels() []string {
+	return s.PhpFpm.SuppressLogLevels
+}
+
+func (s *Settings) GetRedisSuppressLogLevels() []string {
+	return s.Redis.SuppressLogLevels
+}
+
+func (s *Settings) GetResqueSuppressStatuses() []string {
+	return s.Resque.SuppressStatuses
+}
+
+func (s *Settings) GetPostfixSuppressStatuses() []string {
+	return s.Postfix.SuppressStatuses
+}
+
+func (s *Settings) GetPostfixSuppressProcesses() []string {
+	return s.Postfix.SuppressProcesses
+}
+
+func (s *Settings) GetCronSuppressKinds() []string {
+	return s.Cron.SuppressKinds
+}
+
+func (s *Settings) GetKernelSuppressKinds() []string {
+	return s.Kernel.SuppressKinds
+}
+
 func (s *Settings) GetProcessSuppressNames() []string {
 	return s.Process.SuppressNames
 }
@@ -59,3 +217,13 @@ func (s *Settings) GetProcessSuppressNames() []string {
 func (s *Settings) GetGenericSuppressNames() []string {
 	return s.Generic.SuppressNames
 }
+
+func (s *Settings) GetCustomEventSuppressLogLevels(name string) []string {
+	for _, customEvent := range s.CustomEvents {
+		if customEvent.Name == name {
+			return customEvent.SuppressLogLevels
+		}
+	}
+
+	return nil
+}
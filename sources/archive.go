@@ -0,0 +1,156 @@
+package sources
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var archiveRotationRegexp = regexp.MustCompile("\\.([0-9]{1,})$")
+
+// archiveDateRegexp matches the date logrotate's dateext option adds to a
+// rotated file, as -YYYYMMDD, -YYYYMMDDHH or -YYYY-MM-DD.
+var archiveDateRegexp = regexp.MustCompile(
+	"-([0-9]{8}(?:[0-9]{2})?|[0-9]{4}-[0-9]{2}-[0-9]{2})$",
+)
+
+// ArchiveSource reads a set of rotated log files, plain or compressed with
+// gzip or bzip2, one after the other from oldest to newest. Unlike the other
+// sources it doesn't wait for more lines: once the newest file has been read
+// ReadLine returns io.EOF.
+type ArchiveSource struct {
+	Label string
+	Paths []string
+
+	paths  []string
+	file   *os.File
+	reader *bufio.Reader
+	next   int
+}
+
+// Open orders the files by their rotation suffix: syslog.2.gz is older than
+// syslog.1, which is older than syslog. Files rotated with logrotate's
+// dateext option are ordered by their date, syslog-20240101.gz before
+// syslog-20240108.gz, and come before the others. Files with the same suffix
+// (or none) are read in the order they were given. Paths itself is left as it
+// is.
+func (s *ArchiveSource) Open() error {
+	for _, path := range s.Paths {
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+	}
+
+	s.paths = append([]string(nil), s.Paths...)
+
+	sort.SliceStable(s.paths, func(i, j int) bool {
+		dateI, nI := rotation(s.paths[i])
+		dateJ, nJ := rotation(s.paths[j])
+
+		if dateI != dateJ {
+			return dateJ == "" || dateI != "" && dateI < dateJ
+		}
+
+		return nI > nJ
+	})
+
+	return nil
+}
+
+// rotation returns the date, with any dashes removed, of a file rotated to
+// name-DATE, and N for a file rotated to name.N (either may be followed by
+// .gz or .bz2). For anything else it returns "" and 0.
+func rotation(path string) (string, int) {
+	path = strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ".bz2")
+
+	if matches := archiveDateRegexp.FindStringSubmatch(path); matches != nil {
+		return strings.Replace(matches[1], "-", "", -1), 0
+	}
+
+	if matches := archiveRotationRegexp.FindStringSubmatch(path); matches != nil {
+		n, _ := strconv.Atoi(matches[1])
+		return "", n
+	}
+
+	return "", 0
+}
+
+func (s *ArchiveSource) ReadLine() (Line, error) {
+	for {
+		if s.reader == nil {
+			if s.next == len(s.paths) {
+				return Line{}, io.EOF
+			}
+
+			if err := s.openNext(); err != nil {
+				return Line{}, err
+			}
+		}
+
+		line, err := s.reader.ReadString('\n')
+
+		if err == nil || err == io.EOF && line != "" {
+			return Line{Label: s.Label, Text: strings.TrimRight(line, "\r\n")}, nil
+		}
+
+		if err != io.EOF {
+			return Line{}, err
+		}
+
+		s.file.Close()
+		s.reader = nil
+	}
+}
+
+func (s *ArchiveSource) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+
+	return s.file.Close()
+}
+
+func (s *ArchiveSource) openNext() error {
+	path := s.paths[s.next]
+	s.next++
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader = file
+
+	switch filepath.Ext(path) {
+	case ".gz":
+		reader, err = gzip.NewReader(file)
+	case ".bz2":
+		reader = bzip2.NewReader(file)
+	}
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	s.file = file
+	s.reader = bufio.NewReader(reader)
+
+	return nil
+}
+
+func NewArchiveSource(label string, paths []string) *ArchiveSource {
+	return &ArchiveSource{
+		Label: label,
+		Paths: paths,
+	}
+}
@@ -0,0 +1,139 @@
+package sources
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, path string, text string) {
+	file, err := os.Create(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var writer io.Writer = file
+
+	if filepath.Ext(path) == ".gz" {
+		gzipWriter := gzip.NewWriter(file)
+		defer gzipWriter.Close()
+
+		writer = gzipWriter
+	}
+
+	if _, err = io.WriteString(writer, text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestArchive returns every line of an opened archive source.
+func readTestArchive(t *testing.T, source *ArchiveSource) []string {
+	var lines []string
+
+	for {
+		line, err := source.ReadLine()
+
+		if err == io.EOF {
+			return lines
+		}
+
+		if err != nil {
+			t.Fatalf("ReadLine() = %s", err)
+		}
+
+		lines = append(lines, line.Text)
+	}
+}
+
+// TestArchiveSourceOrder checks that archives are read oldest first by their
+// rotation suffix, whatever order they're given in, and that Paths is left
+// alone.
+func TestArchiveSourceOrder(t *testing.T) {
+	directory := t.TempDir()
+
+	syslog := filepath.Join(directory, "syslog")
+	syslog1 := filepath.Join(directory, "syslog.1")
+	syslog2 := filepath.Join(directory, "syslog.2.gz")
+	syslog10 := filepath.Join(directory, "syslog.10.gz")
+
+	writeTestArchive(t, syslog, "Oct 17 10:00:03 web1 app: newest\n")
+	writeTestArchive(t, syslog1, "Oct 17 10:00:02 web1 app: newer\n")
+	writeTestArchive(t, syslog2, "Oct 17 10:00:01 web1 app: older\n")
+	writeTestArchive(t, syslog10, "Oct 17 10:00:00 web1 app: oldest")
+
+	paths := []string{syslog1, syslog, syslog10, syslog2}
+	source := NewArchiveSource("", paths)
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	defer source.Close()
+
+	lines := readTestArchive(t, source)
+	want := []string{
+		"Oct 17 10:00:00 web1 app: oldest",
+		"Oct 17 10:00:01 web1 app: older",
+		"Oct 17 10:00:02 web1 app: newer",
+		"Oct 17 10:00:03 web1 app: newest",
+	}
+
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+
+	if !reflect.DeepEqual(source.Paths, []string{syslog1, syslog, syslog10, syslog2}) {
+		t.Errorf("Paths = %q, want it unchanged", source.Paths)
+	}
+}
+
+// TestArchiveSourceDateOrder checks that archives rotated with logrotate's
+// dateext option are read oldest first by their date, before the live file.
+func TestArchiveSourceDateOrder(t *testing.T) {
+	directory := t.TempDir()
+
+	syslog := filepath.Join(directory, "syslog")
+	syslog0108 := filepath.Join(directory, "syslog-20240108")
+	syslog0101 := filepath.Join(directory, "syslog-20240101.gz")
+	syslog1225 := filepath.Join(directory, "syslog-2023-12-25.gz")
+
+	writeTestArchive(t, syslog, "Jan 15 10:00:00 web1 app: newest\n")
+	writeTestArchive(t, syslog0108, "Jan  8 10:00:00 web1 app: newer\n")
+	writeTestArchive(t, syslog0101, "Jan  1 10:00:00 web1 app: older\n")
+	writeTestArchive(t, syslog1225, "Dec 25 10:00:00 web1 app: oldest\n")
+
+	source := NewArchiveSource("", []string{syslog, syslog0108, syslog0101, syslog1225})
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	defer source.Close()
+
+	lines := readTestArchive(t, source)
+	want := []string{
+		"Dec 25 10:00:00 web1 app: oldest",
+		"Jan  1 10:00:00 web1 app: older",
+		"Jan  8 10:00:00 web1 app: newer",
+		"Jan 15 10:00:00 web1 app: newest",
+	}
+
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestArchiveSourceMissingFile(t *testing.T) {
+	directory := t.TempDir()
+	source := NewArchiveSource("", []string{filepath.Join(directory, "syslog.1")})
+
+	if err := source.Open(); !os.IsNotExist(err) {
+		t.Errorf("Open() = %v, want a missing file error", err)
+	}
+}
@@ -0,0 +1,226 @@
+package sources
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// FileSource follows a local log file, much like tail -F. When the file is
+// rotated (renamed and recreated) the rest of the old file is read before
+// switching to the new one, and when it is truncated in place (copytruncate)
+// reading starts again from the beginning.
+type FileSource struct {
+	Label        string
+	Path         string
+	InitialLines int
+	PollInterval time.Duration
+
+	file    *os.File
+	info    os.FileInfo
+	reader  *bufio.Reader
+	offset  int64
+	partial string
+}
+
+func (s *FileSource) Open() error {
+	file, err := os.Open(s.Path)
+
+	if err != nil {
+		return err
+	}
+
+	if err = seekToLastLines(file, s.InitialLines); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return s.follow(file, offset)
+}
+
+func (s *FileSource) ReadLine() (Line, error) {
+	for {
+		line, err := s.reader.ReadString('\n')
+		s.offset += int64(len(line))
+		s.partial += line
+
+		if err == nil {
+			return s.takePartial(), nil
+		}
+
+		if err != io.EOF {
+			return Line{}, err
+		}
+
+		reopened, err := s.checkRotation()
+
+		if err != nil {
+			return Line{}, err
+		}
+
+		// Whatever was left unterminated at the end of the old file is
+		// never going to be finished, so hand it out as it is.
+		if reopened && s.partial != "" {
+			return s.takePartial(), nil
+		}
+
+		if !reopened {
+			time.Sleep(s.PollInterval)
+		}
+	}
+}
+
+func (s *FileSource) Close() error {
+	return s.file.Close()
+}
+
+func (s *FileSource) follow(file *os.File, offset int64) error {
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	s.file = file
+	s.info = info
+	s.reader = bufio.NewReader(file)
+	s.offset = offset
+
+	return nil
+}
+
+func (s *FileSource) takePartial() Line {
+	line := Line{Label: s.Label, Text: strings.TrimRight(s.partial, "\r\n")}
+	s.partial = ""
+
+	return line
+}
+
+// checkRotation is called once the open file has been read to the end. It
+// reports whether reading should resume from a different position, either
+// because the path now refers to a new file or because the file has been
+// truncated.
+func (s *FileSource) checkRotation() (bool, error) {
+	info, err := os.Stat(s.Path)
+
+	if err != nil {
+		// Between the rename and the create there is no file at all, so keep
+		// waiting on the old one.
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if !os.SameFile(s.info, info) {
+		// Lines may have been written to the old file after we last read
+		// it and before it was renamed.
+		if current, err := s.file.Stat(); err == nil && current.Size() > s.offset {
+			return false, nil
+		}
+
+		file, err := os.Open(s.Path)
+
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		s.file.Close()
+
+		return true, s.follow(file, 0)
+	}
+
+	if info.Size() < s.offset {
+		if _, err = s.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+
+		s.reader.Reset(s.file)
+		s.offset = 0
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func NewFileSource(label string, path string, initialLines int) *FileSource {
+	return &FileSource{
+		Label:        label,
+		Path:         path,
+		InitialLines: initialLines,
+		PollInterval: 250 * time.Millisecond,
+	}
+}
+
+// seekToLastLines positions file so that the next read returns the last
+// count lines, reading backwards from the end in fixed-size blocks.
+func seekToLastLines(file *os.File, count int) error {
+	size, err := file.Seek(0, io.SeekEnd)
+
+	if err != nil || count <= 0 {
+		return err
+	}
+
+	const blockSize = 8192
+
+	block := make([]byte, blockSize)
+	offset := size
+	newlines := 0
+
+	for offset > 0 {
+		length := int64(blockSize)
+
+		if offset < length {
+			length = offset
+		}
+
+		offset -= length
+
+		if _, err = file.ReadAt(block[:length], offset); err != nil {
+			return err
+		}
+
+		for i := length - 1; i >= 0; i-- {
+			if block[i] != '\n' {
+				continue
+			}
+
+			// The newline that terminates the final line doesn't start a
+			// new one.
+			if offset+i == size-1 {
+				continue
+			}
+
+			newlines++
+
+			if newlines == count {
+				_, err = file.Seek(offset+i+1, io.SeekStart)
+
+				return err
+			}
+		}
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+
+	return err
+}
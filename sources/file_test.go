@@ -0,0 +1,112 @@
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestFileSource(t *testing.T, text string, initialLines int) *FileSource {
+	path := filepath.Join(t.TempDir(), "syslog")
+
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewFileSource("web1", path, initialLines)
+	source.PollInterval = 10 * time.Millisecond
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	t.Cleanup(func() { source.Close() })
+
+	return source
+}
+
+func appendTestLines(t *testing.T, path string, text string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	if _, err = file.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectTestFileLines reads a line from source for each of want, failing if
+// a line is missing, repeated or out of order.
+func expectTestFileLines(t *testing.T, source *FileSource, want ...string) {
+	for _, text := range want {
+		result := make(chan Line, 1)
+
+		go func() {
+			line, _ := source.ReadLine()
+			result <- line
+		}()
+
+		select {
+		case line := <-result:
+			if line.Text != text || line.Label != "web1" {
+				t.Fatalf("ReadLine() = %q, want %q", line, text)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("ReadLine() timed out waiting for %q", text)
+		}
+	}
+}
+
+func TestFileSourceInitialLines(t *testing.T) {
+	source := openTestFileSource(t, "one\ntwo\nthree\nfour\n", 2)
+
+	expectTestFileLines(t, source, "three", "four")
+
+	appendTestLines(t, source.Path, "five\n")
+	expectTestFileLines(t, source, "five")
+}
+
+// TestFileSourceRotation checks that when the file is renamed and recreated,
+// the lines written to the old file after it was last read still come before
+// the new file's.
+func TestFileSourceRotation(t *testing.T) {
+	source := openTestFileSource(t, "one\ntwo\n", 10)
+
+	expectTestFileLines(t, source, "one", "two")
+
+	if err := os.Rename(source.Path, source.Path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	appendTestLines(t, source.Path+".1", "three\n")
+	appendTestLines(t, source.Path, "four\nfive\n")
+
+	expectTestFileLines(t, source, "three", "four", "five")
+
+	appendTestLines(t, source.Path, "six\n")
+	expectTestFileLines(t, source, "six")
+}
+
+// TestFileSourceCopyTruncate checks that when the file is truncated in
+// place, reading starts again from the beginning.
+func TestFileSourceCopyTruncate(t *testing.T) {
+	source := openTestFileSource(t, "first line\nsecond line\n", 10)
+
+	expectTestFileLines(t, source, "first line", "second line")
+
+	if err := os.Truncate(source.Path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	appendTestLines(t, source.Path, "three\n")
+	expectTestFileLines(t, source, "three")
+
+	appendTestLines(t, source.Path, "four\n")
+	expectTestFileLines(t, source, "four")
+}
@@ -0,0 +1,235 @@
+package sources
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MultiSource reads from several sources at once and merges their lines in
+// syslog timestamp order. Each line is held back for Window so that lines
+// from slower sources have a chance to be slotted in before it.
+type MultiSource struct {
+	Sources []SourceInterface
+	Window  time.Duration
+
+	incoming chan multiSourceLine
+	done     chan bool
+	closing  sync.Once
+	buffer   []multiSourceLine
+	running  int
+}
+
+type multiSourceLine struct {
+	line     Line
+	time     time.Time
+	received time.Time
+	source   SourceInterface
+	err      error
+}
+
+func (s *MultiSource) Open() error {
+	for i, source := range s.Sources {
+		if err := source.Open(); err != nil {
+			for _, opened := range s.Sources[:i] {
+				opened.Close()
+			}
+
+			return err
+		}
+	}
+
+	s.incoming = make(chan multiSourceLine)
+	s.done = make(chan bool)
+	s.running = len(s.Sources)
+
+	for _, source := range s.Sources {
+		go s.read(source)
+	}
+
+	return nil
+}
+
+func (s *MultiSource) ReadLine() (Line, error) {
+	for {
+		if len(s.buffer) == 0 {
+			if s.running == 0 {
+				return Line{}, io.EOF
+			}
+
+			select {
+			case item := <-s.incoming:
+				s.add(item)
+			case <-s.done:
+				return Line{}, io.EOF
+			}
+
+			continue
+		}
+
+		wait := s.buffer[0].received.Add(s.Window).Sub(time.Now())
+
+		if wait <= 0 || s.running == 0 {
+			line := s.buffer[0].line
+			s.buffer = s.buffer[1:]
+
+			return line, nil
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case item := <-s.incoming:
+			timer.Stop()
+			s.add(item)
+		case <-timer.C:
+		case <-s.done:
+			timer.Stop()
+
+			return Line{}, io.EOF
+		}
+	}
+}
+
+// Close closes every source and stops reading from them, even if ReadLine is
+// no longer being called.
+func (s *MultiSource) Close() error {
+	var err error
+
+	s.closing.Do(func() {
+		if s.done != nil {
+			close(s.done)
+		}
+	})
+
+	for _, source := range s.Sources {
+		if closeErr := source.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// State lists the state of each labelled source that isn't connected.
+func (s *MultiSource) State() string {
+	states := []string{}
+
+	for _, source := range s.Sources {
+		stateSource, ok := source.(StateInterface)
+
+		if !ok {
+			continue
+		}
+
+		if state := stateSource.State(); state != "" {
+			states = append(states, sourceLabel(source)+": "+state)
+		}
+	}
+
+	return strings.Join(states, ", ")
+}
+
+// read passes the lines of source on to ReadLine until the source runs out or
+// the MultiSource is closed.
+func (s *MultiSource) read(source SourceInterface) {
+	for {
+		line, err := source.ReadLine()
+
+		if err != nil {
+			select {
+			case s.incoming <- multiSourceLine{source: source, err: err}:
+			case <-s.done:
+			}
+
+			return
+		}
+
+		item := multiSourceLine{
+			line:     line,
+			received: time.Now(),
+			source:   source,
+		}
+
+		item.time, _ = lineTime(line.Text)
+
+		select {
+		case s.incoming <- item:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// add slots a line into the buffer after every line with the same or an
+// earlier timestamp. Lines without a timestamp go to the end.
+func (s *MultiSource) add(item multiSourceLine) {
+	if item.err != nil {
+		s.running--
+
+		if item.err != io.EOF {
+			log.Printf(
+				"\rCould not read from %s: %s\n",
+				sourceLabel(item.source),
+				item.err,
+			)
+		}
+
+		return
+	}
+
+	index := len(s.buffer)
+
+	if !item.time.IsZero() {
+		for i, buffered := range s.buffer {
+			if buffered.time.After(item.time) {
+				index = i
+				break
+			}
+		}
+	}
+
+	s.buffer = append(s.buffer, multiSourceLine{})
+	copy(s.buffer[index+1:], s.buffer[index:])
+	s.buffer[index] = item
+}
+
+func sourceLabel(source SourceInterface) string {
+	switch source := source.(type) {
+	case *FileSource:
+		if source.Label != "" {
+			return source.Label
+		}
+
+		return source.Path
+	case *SshSource:
+		if source.Label != "" {
+			return source.Label
+		}
+
+		return source.Host
+	case *StdinSource:
+		if source.Label != "" {
+			return source.Label
+		}
+
+		return "stdin"
+	case *SyslogSource:
+		if source.Label != "" {
+			return source.Label
+		}
+
+		return source.Listen
+	}
+
+	return "unknown source"
+}
+
+func NewMultiSource(sources []SourceInterface) *MultiSource {
+	return &MultiSource{
+		Sources: sources,
+		Window:  time.Second,
+	}
+}
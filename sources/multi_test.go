@@ -0,0 +1,167 @@
+package sources
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testSource returns its lines, labelled with label, then io.EOF. If wait is
+// set, io.EOF isn't returned until it's closed; if lines is nil, the source
+// keeps returning the same line.
+type testSource struct {
+	label string
+	lines []string
+	wait  chan bool
+}
+
+func (s *testSource) Open() error { return nil }
+
+func (s *testSource) ReadLine() (Line, error) {
+	if s.lines == nil {
+		return Line{Label: s.label, Text: "Oct 17 10:00:00 web1 app: again"}, nil
+	}
+
+	if len(s.lines) == 0 {
+		if s.wait != nil {
+			<-s.wait
+		}
+
+		return Line{}, io.EOF
+	}
+
+	line := s.lines[0]
+	s.lines = s.lines[1:]
+
+	return Line{Label: s.label, Text: line}, nil
+}
+
+func (s *testSource) Close() error { return nil }
+
+func readTestLines(t *testing.T, source SourceInterface) []Line {
+	lines := []Line{}
+
+	for {
+		line, err := source.ReadLine()
+
+		if err == io.EOF {
+			return lines
+		}
+
+		if err != nil {
+			t.Fatalf("ReadLine() = %s", err)
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+// TestMultiSourceMerges checks that lines from different sources are merged
+// by their timestamps, with lines without one kept after the line read before
+// them, and that every line keeps the label of its source.
+func TestMultiSourceMerges(t *testing.T) {
+	source := NewMultiSource([]SourceInterface{
+		&testSource{label: "web1", lines: []string{
+			"Oct 17 10:00:00 web1 app: one",
+			"Oct 17 10:00:02 web1 app: three",
+			"Oct 17 10:00:04 web1 app: five",
+		}},
+		&testSource{label: "db1", lines: []string{
+			"Oct 17 10:00:01 db1 mysqld: two",
+			"Oct 17 10:00:03 db1 mysqld: four",
+		}},
+	})
+
+	source.Window = 500 * time.Millisecond
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	defer source.Close()
+
+	want := []Line{
+		{"web1", "Oct 17 10:00:00 web1 app: one"},
+		{"db1", "Oct 17 10:00:01 db1 mysqld: two"},
+		{"web1", "Oct 17 10:00:02 web1 app: three"},
+		{"db1", "Oct 17 10:00:03 db1 mysqld: four"},
+		{"web1", "Oct 17 10:00:04 web1 app: five"},
+	}
+
+	if lines := readTestLines(t, source); !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+// TestMultiSourceEOF checks that io.EOF isn't returned until every source has
+// run out.
+func TestMultiSourceEOF(t *testing.T) {
+	slow := &testSource{label: "db1", lines: []string{}, wait: make(chan bool)}
+	source := NewMultiSource([]SourceInterface{
+		&testSource{label: "web1", lines: []string{"Oct 17 10:00:00 web1 app: one"}},
+		slow,
+	})
+
+	source.Window = 10 * time.Millisecond
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	defer source.Close()
+
+	if line, err := source.ReadLine(); err != nil || line.Label != "web1" {
+		t.Fatalf("ReadLine() = %q, %v", line, err)
+	}
+
+	result := make(chan error, 1)
+
+	go func() {
+		_, err := source.ReadLine()
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		t.Fatalf("ReadLine() = %v while db1 is still open", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(slow.wait)
+
+	select {
+	case err := <-result:
+		if err != io.EOF {
+			t.Errorf("ReadLine() = %v, want io.EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadLine() didn't return after every source ran out")
+	}
+}
+
+// TestMultiSourceClose checks that closing the source stops its readers even
+// though ReadLine isn't being called to take their lines.
+func TestMultiSourceClose(t *testing.T) {
+	endless := &testSource{label: "web1"}
+	source := NewMultiSource([]SourceInterface{endless})
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	source.Close()
+
+	stopped := make(chan bool)
+
+	go func() {
+		source.read(endless)
+		stopped <- true
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("read() blocked after Close()")
+	}
+}
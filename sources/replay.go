@@ -0,0 +1,67 @@
+package sources
+
+import (
+	"time"
+)
+
+// ReplaySource passes on the lines of another source, waiting between them
+// for as long as passed between their timestamps, divided by Speed. Lines
+// without a timestamp, or with one earlier than a line before them, are passed
+// on straight away.
+type ReplaySource struct {
+	Source SourceInterface
+	Speed  float64
+
+	// Sleep waits between lines. It defaults to time.Sleep, and is only
+	// replaced by tests.
+	Sleep func(time.Duration)
+
+	lastTime time.Time
+}
+
+func (s *ReplaySource) Open() error {
+	return s.Source.Open()
+}
+
+func (s *ReplaySource) ReadLine() (Line, error) {
+	line, err := s.Source.ReadLine()
+
+	if err != nil {
+		return line, err
+	}
+
+	lineTime_, ok := lineTime(line.Text)
+
+	if !ok {
+		return line, nil
+	}
+
+	if !s.lastTime.IsZero() && !lineTime_.After(s.lastTime) {
+		return line, nil
+	}
+
+	if !s.lastTime.IsZero() {
+		sleep := s.Sleep
+
+		if sleep == nil {
+			sleep = time.Sleep
+		}
+
+		sleep(time.Duration(float64(lineTime_.Sub(s.lastTime)) / s.Speed))
+	}
+
+	s.lastTime = lineTime_
+
+	return line, nil
+}
+
+func (s *ReplaySource) Close() error {
+	return s.Source.Close()
+}
+
+func NewReplaySource(source SourceInterface, speed float64) *ReplaySource {
+	return &ReplaySource{
+		Source: source,
+		Speed:  speed,
+	}
+}
@@ -0,0 +1,37 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplaySource(t *testing.T) {
+	source := NewReplaySource(&testSource{label: "web1", lines: []string{
+		"Oct 17 10:00:00 web1 app: one",
+		"Oct 17 10:00:10 web1 app: ten seconds later",
+		"    continued without a timestamp",
+		"Oct 17 10:00:05 web1 app: logged late",
+		"Oct 17 10:00:30 web1 app: twenty seconds after the latest",
+		"Oct 17 10:00:30 web1 app: at the same time",
+	}}, 10)
+
+	sleeps := []time.Duration{}
+	source.Sleep = func(duration time.Duration) { sleeps = append(sleeps, duration) }
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	defer source.Close()
+
+	if lines := readTestLines(t, source); len(lines) != 6 {
+		t.Errorf("read %d lines, want 6", len(lines))
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second}
+
+	if !reflect.DeepEqual(sleeps, want) {
+		t.Errorf("slept %v, want %v", sleeps, want)
+	}
+}
@@ -0,0 +1,118 @@
+package sources
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	settings "github.com/lovek323/bclog/settings"
+)
+
+// SourceInterface is implemented by everything bclog can read syslog lines
+// from. ReadLine blocks until a line is available and returns io.EOF once the
+// source has no more lines to give.
+type SourceInterface interface {
+	Open() error
+	ReadLine() (Line, error)
+	Close() error
+}
+
+// Line is a single syslog line, along with the label of the source it was
+// read from (empty if the source wasn't given one).
+type Line struct {
+	Label string
+	Text  string
+}
+
+// StateInterface is implemented by sources that can lose and regain their
+// connection, so that the prompt can show what they are doing.
+type StateInterface interface {
+	State() string
+}
+
+// NewSource creates the source described by the settings: the sources listed
+// in the Sources section merged together if there are any, otherwise the one
+// in the Source section. The source still has to be opened before it can be
+// read.
+func NewSource(settings_ *settings.Settings) (SourceInterface, error) {
+	if len(settings_.Sources) == 0 {
+		return newSource(settings_.Source, settings_)
+	}
+
+	sources := make([]SourceInterface, len(settings_.Sources))
+
+	for i, sourceSettings := range settings_.Sources {
+		source, err := newSource(sourceSettings, settings_)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sources[i] = source
+	}
+
+	return NewMultiSource(sources), nil
+}
+
+func newSource(
+	sourceSettings settings.SourceSettings,
+	settings_ *settings.Settings,
+) (SourceInterface, error) {
+	path := sourceSettings.Path
+
+	if path == "" {
+		path = "/var/log/syslog"
+	}
+
+	switch sourceSettings.Type {
+	case "", "ssh":
+		return NewSshSource(
+			sourceSettings,
+			settings_.PrimaryKeyFile,
+			path,
+			settings_.InitialLines,
+		), nil
+	case "file":
+		return NewFileSource(
+			sourceSettings.Label,
+			path,
+			settings_.InitialLines,
+		), nil
+	case "stdin":
+		return NewStdinSource(sourceSettings.Label), nil
+	case "syslog":
+		listen := sourceSettings.Listen
+
+		if listen == "" {
+			listen = ":5514"
+		}
+
+		return NewSyslogSource(sourceSettings.Label, listen), nil
+	}
+
+	return nil, fmt.Errorf("unknown source type: %s", sourceSettings.Type)
+}
+
+var lineTimeRegexp = regexp.MustCompile(
+	"^(?:<[0-9]{1,3}>(?:[0-9]{1,2} )?)?(?P<timestamp>[0-9]{4}-[^ ]+|.{15})",
+)
+
+// lineTime extracts the syslog timestamp from the start of a line, which may
+// be either an RFC 3339 timestamp or the classic "Jan  2 15:04:05" kind.
+// Classic timestamps carry no year, so the result is only good for comparing
+// lines that are close together.
+func lineTime(line string) (time.Time, bool) {
+	matches := lineTimeRegexp.FindStringSubmatch(line)
+
+	if matches == nil {
+		return time.Time{}, false
+	}
+
+	syslogTime, err := time.Parse(time.RFC3339Nano, matches[1])
+
+	if err != nil {
+		syslogTime, err = time.Parse(time.Stamp, matches[1])
+	}
+
+	return syslogTime, err == nil
+}
@@ -0,0 +1,550 @@
+package sources
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	settings "github.com/lovek323/bclog/settings"
+)
+
+const (
+	sshMinBackoff = time.Second
+	sshMaxBackoff = time.Minute
+
+	// sshResumeLines is how far back the log is re-read after reconnecting.
+	// Lines that were already seen before the connection dropped are
+	// skipped, so this only needs to cover the time spent disconnected.
+	sshResumeLines = 1000
+)
+
+// SshSource tails a log file on a remote machine (by default the vagrant VM)
+// over an in-process ssh connection. If the connection drops it reconnects
+// with exponential backoff and carries on from the last line it returned.
+type SshSource struct {
+	Label          string
+	Host           string
+	Port           int
+	User           string
+	PrimaryKeyFile string
+	KnownHostsFile string
+	HostKeyPolicy  string
+	UseAgent       bool
+	Path           string
+	InitialLines   int
+
+	// Dial opens the connection the ssh session runs over. It defaults to a
+	// TCP connection with a timeout, and is only replaced by tests.
+	Dial func(network, address string) (net.Conn, error)
+
+	agent     net.Conn
+	reader    *bufio.Reader
+	backoff   time.Duration
+	openError error
+
+	// mutex guards client and session too, since Close disconnects from
+	// another goroutine while ReadLine reconnects.
+	mutex   sync.Mutex
+	client  *ssh.Client
+	session *ssh.Session
+	state   string
+	closed  bool
+	done    chan bool
+
+	resuming  bool
+	lastTime  time.Time
+	lastLines map[string]bool
+}
+
+// Open connects to the remote machine and starts the tail. A failure that
+// retrying could fix doesn't stop Open from returning: ReadLine retries with
+// backoff, just like after a dropped connection, so that a machine that's
+// down doesn't hold up the other sources.
+func (s *SshSource) Open() error {
+	s.backoff = sshMinBackoff
+	s.done = make(chan bool)
+	s.setState("connecting")
+
+	err := s.connect(s.InitialLines)
+
+	if err != nil && isPermanentSshError(err) {
+		s.setState("disconnected")
+
+		return err
+	}
+
+	s.openError = err
+
+	return nil
+}
+
+func (s *SshSource) ReadLine() (Line, error) {
+	if s.reader == nil {
+		if err := s.reconnect(s.openError); err != nil {
+			return Line{}, err
+		}
+	}
+
+	for {
+		line, err := s.reader.ReadString('\n')
+
+		if err != nil && (line == "" || err != io.EOF) {
+			if s.isClosed() {
+				return Line{}, io.EOF
+			}
+
+			if err = s.reconnect(err); err != nil {
+				return Line{}, err
+			}
+
+			continue
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		s.backoff = sshMinBackoff
+		s.setState("")
+
+		if s.isDuplicate(line) {
+			continue
+		}
+
+		s.remember(line)
+
+		return Line{Label: s.Label, Text: line}, nil
+	}
+}
+
+func (s *SshSource) Close() error {
+	s.mutex.Lock()
+
+	if !s.closed && s.done != nil {
+		close(s.done)
+	}
+
+	s.closed = true
+	s.mutex.Unlock()
+
+	s.disconnect()
+
+	return nil
+}
+
+func (s *SshSource) State() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.state
+}
+
+func (s *SshSource) connect(lines int) error {
+	config, err := s.clientConfig()
+
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(s.Host, strconv.FormatInt(int64(s.Port), 10))
+
+	client, err := s.dial(address, config)
+
+	// The agent is only needed while authenticating.
+	if s.agent != nil {
+		s.agent.Close()
+		s.agent = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	session, err := client.NewSession()
+
+	if err != nil {
+		client.Close()
+
+		return err
+	}
+
+	stdout, err := session.StdoutPipe()
+
+	if err != nil {
+		session.Close()
+		client.Close()
+
+		return err
+	}
+
+	command := "sudo tail -n " + strconv.FormatInt(int64(lines), 10) +
+		" -F " + shellQuote(s.Path)
+
+	if err = session.Start(command); err != nil {
+		session.Close()
+		client.Close()
+
+		return err
+	}
+
+	s.mutex.Lock()
+	closed := s.closed
+
+	if !closed {
+		s.client = client
+		s.session = session
+	}
+
+	s.mutex.Unlock()
+
+	if closed {
+		session.Close()
+		client.Close()
+
+		return io.EOF
+	}
+
+	s.reader = bufio.NewReader(stdout)
+
+	return nil
+}
+
+// dial opens the connection to address with Dial and starts an ssh session
+// over it.
+func (s *SshSource) dial(
+	address string,
+	config *ssh.ClientConfig,
+) (*ssh.Client, error) {
+	dial := s.Dial
+
+	if dial == nil {
+		dial = func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, config.Timeout)
+		}
+	}
+
+	connection, err := dial("tcp", address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	clientConnection, channels, requests, err := ssh.NewClientConn(
+		connection,
+		address,
+		config,
+	)
+
+	if err != nil {
+		connection.Close()
+
+		return nil, err
+	}
+
+	return ssh.NewClient(clientConnection, channels, requests), nil
+}
+
+func (s *SshSource) disconnect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.session != nil {
+		s.session.Close()
+	}
+
+	if s.client != nil {
+		s.client.Close()
+	}
+}
+
+func (s *SshSource) clientConfig() (*ssh.ClientConfig, error) {
+	var methods []ssh.AuthMethod
+
+	if s.PrimaryKeyFile != "" {
+		key, err := ioutil.ReadFile(s.PrimaryKeyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read primary key file: %s", err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse primary key file: %s", err)
+		}
+
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if s.UseAgent && os.Getenv("SSH_AUTH_SOCK") != "" {
+		connection, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
+
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to ssh-agent: %s", err)
+		}
+
+		s.agent = connection
+
+		methods = append(
+			methods,
+			ssh.PublicKeysCallback(agent.NewClient(connection).Signers),
+		)
+	}
+
+	hostKeyCallback, err := s.hostKeyCallback()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            s.User,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         10 * time.Second,
+	}, nil
+}
+
+// hostKeyCallback checks the server's key against known_hosts according to
+// HostKeyPolicy: "strict" only accepts hosts that are already known,
+// "accept-new" adds unknown hosts to the file but still rejects changed keys,
+// and "insecure" accepts anything.
+func (s *SshSource) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if s.HostKeyPolicy == "insecure" {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	if s.HostKeyPolicy != "strict" && s.HostKeyPolicy != "accept-new" {
+		return nil, fmt.Errorf("unknown host key policy: %s", s.HostKeyPolicy)
+	}
+
+	if s.HostKeyPolicy == "accept-new" {
+		// knownhosts.New refuses to load a file that doesn't exist yet.
+		file, err := os.OpenFile(s.KnownHostsFile, os.O_CREATE|os.O_RDONLY, 0600)
+
+		if err != nil {
+			return nil, err
+		}
+
+		file.Close()
+	}
+
+	callback, err := knownhosts.New(s.KnownHostsFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.HostKeyPolicy == "strict" {
+		return callback, nil
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+
+		var keyError *knownhosts.KeyError
+
+		if !errors.As(err, &keyError) || len(keyError.Want) > 0 {
+			return err
+		}
+
+		file, err := os.OpenFile(
+			s.KnownHostsFile,
+			os.O_APPEND|os.O_WRONLY,
+			0600,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+		_, err = fmt.Fprintln(file, line)
+
+		return err
+	}, nil
+}
+
+// reconnect keeps trying to (re)start the tail until it succeeds, waiting
+// twice as long after each failure. Errors that retrying can't fix, i.e.,
+// failing to authenticate or a changed host key, are returned instead, and
+// io.EOF once the source is closed.
+func (s *SshSource) reconnect(cause error) error {
+	s.disconnect()
+
+	for {
+		if s.isClosed() {
+			return io.EOF
+		}
+
+		log.Printf(
+			"\rNo connection to log tail (%s), reconnecting in %s\n",
+			cause,
+			s.backoff,
+		)
+		s.setState("reconnecting in " + s.backoff.String())
+
+		select {
+		case <-time.After(s.backoff):
+		case <-s.done:
+			return io.EOF
+		}
+
+		s.backoff *= 2
+
+		if s.backoff > sshMaxBackoff {
+			s.backoff = sshMaxBackoff
+		}
+
+		s.setState("connecting")
+
+		// Only a tail that has already returned lines has anything to
+		// resume from.
+		resuming := s.lastLines != nil
+		lines := s.InitialLines
+
+		if resuming && lines < sshResumeLines {
+			lines = sshResumeLines
+		}
+
+		cause = s.connect(lines)
+
+		if cause == nil {
+			s.resuming = resuming
+
+			return nil
+		}
+
+		if isPermanentSshError(cause) {
+			s.setState("disconnected")
+
+			return cause
+		}
+	}
+}
+
+// isDuplicate reports whether a line read after reconnecting was already
+// returned before the connection dropped.
+func (s *SshSource) isDuplicate(line string) bool {
+	if !s.resuming {
+		return false
+	}
+
+	lineTime_, ok := lineTime(line)
+
+	if ok && lineTime_.Before(s.lastTime) || s.lastLines[line] {
+		return true
+	}
+
+	s.resuming = false
+
+	return false
+}
+
+func (s *SshSource) remember(line string) {
+	lineTime_, ok := lineTime(line)
+
+	if !ok {
+		return
+	}
+
+	if !lineTime_.Equal(s.lastTime) {
+		s.lastTime = lineTime_
+		s.lastLines = make(map[string]bool)
+	}
+
+	s.lastLines[line] = true
+}
+
+func (s *SshSource) setState(state string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state = state
+}
+
+func (s *SshSource) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closed
+}
+
+func isPermanentSshError(err error) bool {
+	var keyError *knownhosts.KeyError
+
+	if errors.As(err, &keyError) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "unable to authenticate")
+}
+
+// shellQuote quotes a value for the remote shell, so that paths with spaces or
+// other special characters reach tail as they are.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", "'\\''", -1) + "'"
+}
+
+func NewSshSource(
+	sourceSettings settings.SourceSettings,
+	primaryKeyFile string,
+	path string,
+	initialLines int,
+) *SshSource {
+	source := &SshSource{
+		Label:          sourceSettings.Label,
+		Host:           sourceSettings.Host,
+		Port:           sourceSettings.Port,
+		User:           sourceSettings.User,
+		PrimaryKeyFile: primaryKeyFile,
+		KnownHostsFile: sourceSettings.KnownHostsFile,
+		HostKeyPolicy:  sourceSettings.HostKeyPolicy,
+		UseAgent:       sourceSettings.UseAgent,
+		Path:           path,
+		InitialLines:   initialLines,
+	}
+
+	if source.Host == "" {
+		source.Host = "localhost"
+	}
+
+	if source.Port == 0 {
+		source.Port = 2222
+	}
+
+	if source.User == "" {
+		source.User = "vagrant"
+	}
+
+	if source.HostKeyPolicy == "" {
+		source.HostKeyPolicy = "accept-new"
+	}
+
+	if source.KnownHostsFile == "" {
+		if user_, err := user.Current(); err == nil {
+			source.KnownHostsFile = filepath.Join(
+				user_.HomeDir,
+				".ssh",
+				"known_hosts",
+			)
+		}
+	}
+
+	return source
+}
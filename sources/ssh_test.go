@@ -0,0 +1,396 @@
+package sources
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSshServer is an in-process ssh server that answers each connection's
+// exec request with the next set of lines in outputs. Every connection but the
+// last is dropped once its lines are written.
+type testSshServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.Signer
+
+	mutex       sync.Mutex
+	outputs     [][]string
+	connections int
+	commands    []string
+}
+
+func newTestSshServer(
+	t *testing.T,
+	clientKey ssh.PublicKey,
+	outputs [][]string,
+) *testSshServer {
+	_, hostPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostKey, err := ssh.NewSignerFromKey(hostPrivateKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &testSshServer{
+		listener: listener,
+		hostKey:  hostKey,
+		outputs:  outputs,
+	}
+
+	server.config = &ssh.ServerConfig{
+		PublicKeyCallback: func(
+			metadata ssh.ConnMetadata,
+			key ssh.PublicKey,
+		) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+
+			return nil, errors.New("unknown key")
+		},
+	}
+	server.config.AddHostKey(hostKey)
+
+	go server.serve()
+
+	t.Cleanup(func() { listener.Close() })
+
+	return server
+}
+
+func (t *testSshServer) serve() {
+	for {
+		connection, err := t.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		go t.handle(connection)
+	}
+}
+
+func (t *testSshServer) handle(connection net.Conn) {
+	serverConnection, channels, requests, err := ssh.NewServerConn(
+		connection,
+		t.config,
+	)
+
+	if err != nil {
+		connection.Close()
+		return
+	}
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+
+		if err != nil {
+			return
+		}
+
+		for request := range channelRequests {
+			if request.Type != "exec" {
+				request.Reply(false, nil)
+				continue
+			}
+
+			var payload struct{ Command string }
+
+			ssh.Unmarshal(request.Payload, &payload)
+			request.Reply(true, nil)
+
+			t.mutex.Lock()
+			t.commands = append(t.commands, payload.Command)
+			output := t.outputs[t.connections]
+			last := t.connections == len(t.outputs)-1
+			t.connections++
+			t.mutex.Unlock()
+
+			for _, line := range output {
+				channel.Write([]byte(line + "\n"))
+			}
+
+			if !last {
+				channel.Close()
+				serverConnection.Close()
+			}
+		}
+	}
+}
+
+// newTestSshSource returns a source that reaches server through Dial, with a
+// known_hosts file trusting hostKey and a primary key file for clientKey.
+func newTestSshSource(
+	t *testing.T,
+	server *testSshServer,
+	hostKey ssh.PublicKey,
+	clientKey ed25519.PrivateKey,
+) *SshSource {
+	directory := t.TempDir()
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join(directory, "id_ed25519")
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(block), 0600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	knownHostsFile := filepath.Join(directory, "known_hosts")
+	line := knownhosts.Line(
+		[]string{knownhosts.Normalize("localhost:2222")},
+		hostKey,
+	)
+	err = ioutil.WriteFile(knownHostsFile, []byte(line+"\n"), 0600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &SshSource{
+		Host:           "localhost",
+		Port:           2222,
+		User:           "vagrant",
+		PrimaryKeyFile: keyFile,
+		KnownHostsFile: knownHostsFile,
+		HostKeyPolicy:  "strict",
+		Path:           "/var/log/my syslog",
+		InitialLines:   10,
+		Dial: func(network, address string) (net.Conn, error) {
+			return net.Dial("tcp", server.listener.Addr().String())
+		},
+	}
+}
+
+func newTestClientKey(t *testing.T) (ssh.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sshPublicKey, privateKey
+}
+
+func TestSshSourceReadsLines(t *testing.T) {
+	clientPublicKey, clientPrivateKey := newTestClientKey(t)
+	server := newTestSshServer(t, clientPublicKey, [][]string{
+		{"Oct 17 10:00:00 web1 app: first", "Oct 17 10:00:01 web1 app: second"},
+	})
+	source := newTestSshSource(
+		t,
+		server,
+		server.hostKey.PublicKey(),
+		clientPrivateKey,
+	)
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	defer source.Close()
+
+	for _, want := range []string{
+		"Oct 17 10:00:00 web1 app: first",
+		"Oct 17 10:00:01 web1 app: second",
+	} {
+		line, err := source.ReadLine()
+
+		if err != nil || line.Text != want {
+			t.Fatalf("ReadLine() = %q, %v, want %q", line.Text, err, want)
+		}
+	}
+
+	want := "sudo tail -n 10 -F '/var/log/my syslog'"
+
+	if server.commands[0] != want {
+		t.Errorf("command = %q, want %q", server.commands[0], want)
+	}
+}
+
+func TestSshSourceAuthenticationFailure(t *testing.T) {
+	clientPublicKey, _ := newTestClientKey(t)
+	_, otherPrivateKey := newTestClientKey(t)
+	server := newTestSshServer(t, clientPublicKey, [][]string{{}})
+	source := newTestSshSource(
+		t,
+		server,
+		server.hostKey.PublicKey(),
+		otherPrivateKey,
+	)
+
+	err := source.Open()
+
+	if err == nil || !strings.Contains(err.Error(), "unable to authenticate") {
+		t.Fatalf("Open() = %v, want an authentication error", err)
+	}
+
+	if source.State() != "disconnected" {
+		t.Errorf("State() = %q, want disconnected", source.State())
+	}
+}
+
+func TestSshSourceHostKeyMismatch(t *testing.T) {
+	clientPublicKey, clientPrivateKey := newTestClientKey(t)
+	otherHostKey, _ := newTestClientKey(t)
+	server := newTestSshServer(t, clientPublicKey, [][]string{{}})
+	source := newTestSshSource(t, server, otherHostKey, clientPrivateKey)
+
+	err := source.Open()
+
+	var keyError *knownhosts.KeyError
+
+	if !errors.As(err, &keyError) || len(keyError.Want) == 0 {
+		t.Fatalf("Open() = %v, want a host key mismatch", err)
+	}
+}
+
+// TestSshSourceReconnects checks that a failed first connection is retried,
+// and that after the tail drops the lines already returned aren't returned
+// again.
+func TestSshSourceReconnects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect backoff")
+	}
+
+	clientPublicKey, clientPrivateKey := newTestClientKey(t)
+	server := newTestSshServer(t, clientPublicKey, [][]string{
+		{"Oct 17 10:00:00 web1 app: first", "Oct 17 10:00:01 web1 app: second"},
+		{
+			"Oct 17 10:00:00 web1 app: first",
+			"Oct 17 10:00:01 web1 app: second",
+			"Oct 17 10:00:02 web1 app: third",
+		},
+	})
+	source := newTestSshSource(
+		t,
+		server,
+		server.hostKey.PublicKey(),
+		clientPrivateKey,
+	)
+
+	dial := source.Dial
+	failures := 1
+
+	source.Dial = func(network, address string) (net.Conn, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("connection refused")
+		}
+
+		return dial(network, address)
+	}
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	defer source.Close()
+
+	for _, want := range []string{
+		"Oct 17 10:00:00 web1 app: first",
+		"Oct 17 10:00:01 web1 app: second",
+		"Oct 17 10:00:02 web1 app: third",
+	} {
+		line, err := source.ReadLine()
+
+		if err != nil || line.Text != want {
+			t.Fatalf("ReadLine() = %q, %v, want %q", line.Text, err, want)
+		}
+	}
+
+	if len(server.commands) != 2 ||
+		!strings.HasPrefix(server.commands[1], "sudo tail -n 1000 ") {
+		t.Errorf("commands = %q, want a resumed tail", server.commands)
+	}
+}
+
+// TestSshSourceOpenUnreachable checks that Open returns while the machine is
+// down, leaving the retries to ReadLine, and that closing the source stops
+// them.
+func TestSshSourceOpenUnreachable(t *testing.T) {
+	source := &SshSource{
+		Label:         "vm",
+		Host:          "localhost",
+		Port:          2222,
+		User:          "vagrant",
+		HostKeyPolicy: "insecure",
+		Path:          "/var/log/syslog",
+		Dial: func(network, address string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	opened := make(chan error, 1)
+
+	go func() { opened <- source.Open() }()
+
+	select {
+	case err := <-opened:
+		if err != nil {
+			t.Fatalf("Open() = %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Open() waited for the machine to come up")
+	}
+
+	result := make(chan error, 1)
+
+	go func() {
+		_, err := source.ReadLine()
+		result <- err
+	}()
+
+	source.Close()
+
+	select {
+	case err := <-result:
+		if err != io.EOF {
+			t.Errorf("ReadLine() = %v, want io.EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadLine() kept retrying after Close()")
+	}
+}
@@ -0,0 +1,38 @@
+package sources
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+type StdinSource struct {
+	Label string
+
+	reader *bufio.Reader
+}
+
+func (s *StdinSource) Open() error {
+	s.reader = bufio.NewReader(os.Stdin)
+
+	return nil
+}
+
+func (s *StdinSource) ReadLine() (Line, error) {
+	line, err := s.reader.ReadString('\n')
+
+	if err != nil && (line == "" || err != io.EOF) {
+		return Line{}, err
+	}
+
+	return Line{Label: s.Label, Text: strings.TrimRight(line, "\r\n")}, nil
+}
+
+func (s *StdinSource) Close() error {
+	return nil
+}
+
+func NewStdinSource(label string) *StdinSource {
+	return &StdinSource{Label: label}
+}
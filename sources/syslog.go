@@ -0,0 +1,322 @@
+package sources
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var syslogPriorityRegexp = regexp.MustCompile("^<[0-9]{1,3}>")
+
+// syslogNilHeaderRegexp matches the start of an RFC 5424 message whose
+// timestamp or hostname is the NILVALUE "-".
+var syslogNilHeaderRegexp = regexp.MustCompile(
+	"^(?P<version><[0-9]{1,3}>[0-9]{1,2} )(?P<timestamp>[^ ]+) (?P<hostname>[^ ]+) ",
+)
+
+// syslogClassicHeaderRegexp and syslogRFC5424HeaderRegexp match the header of
+// a message after syslogLine, up to and including the tag or message ID.
+var syslogClassicHeaderRegexp = regexp.MustCompile(
+	"^(?:<[0-9]{1,3}>)?(?:[0-9]{4}-[^ ]+|.{15}) [^ ]+ (?:[^ ]+: )?",
+)
+var syslogRFC5424HeaderRegexp = regexp.MustCompile(
+	"^<[0-9]{1,3}>[0-9]{1,2} (?:[^ ]+ ){5}",
+)
+
+// syslogMaxFrame is the largest message accepted, over UDP or TCP. A TCP
+// sender that announces a larger octet-counted frame is disconnected.
+const syslogMaxFrame = 65536
+
+// SyslogSource is a syslog server: it accepts messages forwarded by rsyslog
+// and friends over UDP and TCP on the Listen address. Both RFC 3164 and
+// RFC 5424 messages are understood, and TCP streams may use either newline
+// or octet-counted framing (RFC 6587).
+type SyslogSource struct {
+	Label  string
+	Listen string
+
+	packetConn net.PacketConn
+	listener   net.Listener
+	lines      chan Line
+
+	// lines is closed once every receiver has stopped, and done is closed
+	// to stop them.
+	receivers   sync.WaitGroup
+	done        chan bool
+	mutex       sync.Mutex
+	connections map[net.Conn]bool
+}
+
+func (s *SyslogSource) Open() error {
+	packetConn, err := net.ListenPacket("udp", s.Listen)
+
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.Listen)
+
+	if err != nil {
+		packetConn.Close()
+
+		return err
+	}
+
+	s.packetConn = packetConn
+	s.listener = listener
+	s.lines = make(chan Line, 1000)
+	s.done = make(chan bool)
+	s.connections = make(map[net.Conn]bool)
+
+	s.receivers.Add(2)
+
+	go s.receivePackets()
+	go s.acceptConnections()
+
+	go func() {
+		s.receivers.Wait()
+		close(s.lines)
+	}()
+
+	return nil
+}
+
+func (s *SyslogSource) ReadLine() (Line, error) {
+	line, ok := <-s.lines
+
+	if !ok {
+		return Line{}, io.EOF
+	}
+
+	return line, nil
+}
+
+// Close stops the listeners and every open TCP connection. ReadLine returns
+// io.EOF once the receivers have all stopped.
+func (s *SyslogSource) Close() error {
+	s.mutex.Lock()
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+
+	for connection := range s.connections {
+		connection.Close()
+	}
+
+	s.mutex.Unlock()
+
+	s.listener.Close()
+
+	return s.packetConn.Close()
+}
+
+func (s *SyslogSource) receivePackets() {
+	defer s.receivers.Done()
+
+	buffer := make([]byte, syslogMaxFrame)
+
+	for {
+		length, address, err := s.packetConn.ReadFrom(buffer)
+
+		if err != nil {
+			return
+		}
+
+		s.receive(string(buffer[:length]), address)
+	}
+}
+
+func (s *SyslogSource) acceptConnections() {
+	defer s.receivers.Done()
+
+	for {
+		connection, err := s.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+
+		select {
+		case <-s.done:
+			s.mutex.Unlock()
+			connection.Close()
+
+			return
+		default:
+		}
+
+		s.connections[connection] = true
+		s.receivers.Add(1)
+		s.mutex.Unlock()
+
+		go s.receiveStream(connection)
+	}
+}
+
+// receiveStream reads messages from a TCP connection. Octet-counted messages
+// start with their length, and newline-delimited ones start with the "<" of
+// their priority, so the first byte of each message tells them apart.
+func (s *SyslogSource) receiveStream(connection net.Conn) {
+	defer s.receivers.Done()
+	defer connection.Close()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.connections, connection)
+		s.mutex.Unlock()
+	}()
+
+	reader := bufio.NewReader(connection)
+
+	for {
+		first, err := reader.Peek(1)
+
+		if err != nil {
+			return
+		}
+
+		var message string
+
+		if first[0] >= '0' && first[0] <= '9' {
+			lengthText, err := reader.ReadString(' ')
+
+			if err != nil {
+				return
+			}
+
+			length, err := strconv.ParseInt(strings.TrimSpace(lengthText), 10, 32)
+
+			if err == nil && (length < 0 || length > syslogMaxFrame) {
+				err = fmt.Errorf("frame too large")
+			}
+
+			if err != nil {
+				log.Printf(
+					"\rInvalid syslog frame length from %s: %s (%s)\n",
+					connection.RemoteAddr(),
+					strings.TrimSpace(lengthText),
+					err,
+				)
+
+				return
+			}
+
+			frame := make([]byte, length)
+
+			if _, err = io.ReadFull(reader, frame); err != nil {
+				return
+			}
+
+			message = string(frame)
+		} else {
+			message, err = reader.ReadString('\n')
+
+			if err != nil && message == "" {
+				return
+			}
+		}
+
+		s.receive(message, connection.RemoteAddr())
+	}
+}
+
+func (s *SyslogSource) receive(message string, address net.Addr) {
+	message = strings.TrimRight(message, "\r\n\x00")
+
+	if message == "" {
+		return
+	}
+
+	// A message may span several lines, as when rsyslog forwards a stack
+	// trace in one octet-counted frame. Each line after the first is sent
+	// on with the header of the first, so it's parsed as part of the same
+	// program's output.
+	lines := strings.Split(message, "\n")
+	first := syslogLine(strings.TrimRight(lines[0], "\r"), address)
+	header := syslogHeader(first)
+
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+
+		if i == 0 {
+			line = first
+		} else if line == "" {
+			continue
+		} else {
+			line = header + line
+		}
+
+		select {
+		case s.lines <- Line{Label: s.Label, Text: line}:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// syslogHeader returns the header of a line returned by syslogLine, to be
+// repeated on the lines that follow it in the same message. The structured
+// data of RFC 5424 messages isn't repeated.
+func syslogHeader(line string) string {
+	if header := syslogRFC5424HeaderRegexp.FindString(line); header != "" {
+		return header + "- "
+	}
+
+	return syslogClassicHeaderRegexp.FindString(line)
+}
+
+// syslogLine fills in the timestamp and hostname of messages from senders that
+// leave them out, including RFC 5424 messages that give them as "-".
+// Everything else, including the priority, is passed through as it is.
+func syslogLine(message string, address net.Addr) string {
+	host, _, err := net.SplitHostPort(address.String())
+
+	if err != nil {
+		host = address.String()
+	}
+
+	if matches := syslogNilHeaderRegexp.FindStringSubmatch(message); matches != nil &&
+		(matches[2] == "-" || matches[3] == "-") {
+		timestamp := matches[2]
+		hostname := matches[3]
+
+		if timestamp == "-" {
+			timestamp = time.Now().Format(time.RFC3339Nano)
+		}
+
+		if hostname == "-" {
+			hostname = host
+		}
+
+		return matches[1] + timestamp + " " + hostname + " " +
+			message[len(matches[0]):]
+	}
+
+	if _, ok := lineTime(message); ok {
+		return message
+	}
+
+	priority := syslogPriorityRegexp.FindString(message)
+
+	return priority + time.Now().Format(time.Stamp) + " " + host + " " +
+		message[len(priority):]
+}
+
+func NewSyslogSource(label string, listen string) *SyslogSource {
+	return &SyslogSource{
+		Label:  label,
+		Listen: listen,
+	}
+}
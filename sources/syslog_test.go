@@ -0,0 +1,219 @@
+package sources
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestSyslogSource(t *testing.T) *SyslogSource {
+	source := NewSyslogSource("syslog", "127.0.0.1:0")
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open() = %s", err)
+	}
+
+	t.Cleanup(func() { source.Close() })
+
+	return source
+}
+
+func dialTestSyslogSource(t *testing.T, source *SyslogSource) net.Conn {
+	connection, err := net.Dial("tcp", source.listener.Addr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { connection.Close() })
+
+	return connection
+}
+
+func readTestLine(t *testing.T, source *SyslogSource) string {
+	result := make(chan Line, 1)
+
+	go func() {
+		line, _ := source.ReadLine()
+		result <- line
+	}()
+
+	select {
+	case line := <-result:
+		return line.Text
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadLine() timed out")
+	}
+
+	return ""
+}
+
+func TestSyslogSourceFraming(t *testing.T) {
+	source := openTestSyslogSource(t)
+	connection := dialTestSyslogSource(t, source)
+
+	octet := "<13>Oct 17 10:00:01 web1 app: octet"
+	multiline := "<13>Oct 17 10:00:02 web1 app: two\nlines"
+
+	io.WriteString(
+		connection,
+		"<13>Oct 17 10:00:00 web1 app: newline\n"+
+			strconv.Itoa(len(octet))+" "+octet+
+			strconv.Itoa(len(multiline))+" "+multiline,
+	)
+
+	for _, want := range []string{
+		"<13>Oct 17 10:00:00 web1 app: newline",
+		octet,
+		"<13>Oct 17 10:00:02 web1 app: two",
+		"<13>Oct 17 10:00:02 web1 app: lines",
+	} {
+		if line := readTestLine(t, source); line != want {
+			t.Errorf("ReadLine() = %q, want %q", line, want)
+		}
+	}
+}
+
+// TestSyslogSourceMultiline checks that each line of a multi-line RFC 5424
+// message is sent on with the header of the first.
+func TestSyslogSourceMultiline(t *testing.T) {
+	source := openTestSyslogSource(t)
+	connection := dialTestSyslogSource(t, source)
+
+	message := "<11>1 2026-10-17T10:00:00Z web1 php 123 - [origin ip=\"10.0.0.1\"] " +
+		"PHP Fatal error:  Uncaught Exception: boom\r\n" +
+		"Stack trace:\r\n\r\n" +
+		"#0 {main}"
+
+	io.WriteString(connection, strconv.Itoa(len(message))+" "+message)
+
+	for _, want := range []string{
+		"<11>1 2026-10-17T10:00:00Z web1 php 123 - [origin ip=\"10.0.0.1\"] " +
+			"PHP Fatal error:  Uncaught Exception: boom",
+		"<11>1 2026-10-17T10:00:00Z web1 php 123 - - Stack trace:",
+		"<11>1 2026-10-17T10:00:00Z web1 php 123 - - #0 {main}",
+	} {
+		if line := readTestLine(t, source); line != want {
+			t.Errorf("ReadLine() = %q, want %q", line, want)
+		}
+	}
+}
+
+func TestSyslogSourcePackets(t *testing.T) {
+	source := openTestSyslogSource(t)
+	connection, err := net.Dial("udp", source.packetConn.LocalAddr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer connection.Close()
+
+	io.WriteString(connection, "<13>Oct 17 10:00:00 web1 app: packet\n")
+
+	want := "<13>Oct 17 10:00:00 web1 app: packet"
+
+	if line := readTestLine(t, source); line != want {
+		t.Errorf("ReadLine() = %q, want %q", line, want)
+	}
+}
+
+// TestSyslogSourceOversizeFrame checks that a sender announcing a frame larger
+// than syslogMaxFrame is disconnected without anything being read.
+func TestSyslogSourceOversizeFrame(t *testing.T) {
+	source := openTestSyslogSource(t)
+	connection := dialTestSyslogSource(t, source)
+
+	io.WriteString(connection, "2000000000 <13>Oct 17 10:00:00 web1 app: big")
+
+	connection.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := connection.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read() = %v, want the connection closed", err)
+	}
+}
+
+// TestSyslogSourceClose checks that closing the source with a sender still
+// connected ends ReadLine with io.EOF.
+func TestSyslogSourceClose(t *testing.T) {
+	source := openTestSyslogSource(t)
+	connection := dialTestSyslogSource(t, source)
+
+	io.WriteString(connection, "<13>Oct 17 10:00:00 web1 app: first\n")
+	readTestLine(t, source)
+
+	source.Close()
+
+	result := make(chan error, 1)
+
+	go func() {
+		for {
+			if _, err := source.ReadLine(); err != nil {
+				result <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-result:
+		if err != io.EOF {
+			t.Errorf("ReadLine() = %v, want io.EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadLine() didn't return after Close()")
+	}
+}
+
+func TestSyslogLine(t *testing.T) {
+	address := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 514}
+
+	for _, test := range []struct {
+		message string
+		want    string
+	}{
+		{
+			"<13>Oct 17 10:00:00 web1 app: hello",
+			"<13>Oct 17 10:00:00 web1 app: hello",
+		},
+		{
+			"<13>1 2026-10-17T10:00:00Z web1 app 123 - - hello",
+			"<13>1 2026-10-17T10:00:00Z web1 app 123 - - hello",
+		},
+		{
+			"<13>1 2026-10-17T10:00:00Z - app 123 - - hello",
+			"<13>1 2026-10-17T10:00:00Z 10.0.0.5 app 123 - - hello",
+		},
+	} {
+		if line := syslogLine(test.message, address); line != test.want {
+			t.Errorf("syslogLine(%q) = %q, want %q", test.message, line, test.want)
+		}
+	}
+}
+
+// TestSyslogLineNilTimestamp checks that an RFC 5424 message without a
+// timestamp is given the time it was received.
+func TestSyslogLineNilTimestamp(t *testing.T) {
+	address := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 514}
+
+	before := time.Now().Add(-time.Second)
+	line := syslogLine("<13>1 - web1 app 123 - - hello", address)
+	fields := strings.SplitN(line, " ", 3)
+
+	received, err := time.Parse(time.RFC3339Nano, fields[1])
+
+	if err != nil || received.Before(before) || received.After(time.Now()) {
+		t.Fatalf("syslogLine() = %q, want the receive time", line)
+	}
+
+	if _, ok := lineTime(line); !ok {
+		t.Errorf("lineTime(%q) failed", line)
+	}
+
+	if !strings.HasSuffix(line, " web1 app 123 - - hello") {
+		t.Errorf("syslogLine() = %q, want the rest kept", line)
+	}
+}